@@ -0,0 +1,144 @@
+package cirsim
+
+import (
+	"math"
+	"math/cmplx"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// acValue is the phasor of the source in the small-signal analysis.
+type acValue struct {
+	magnitude float64
+	phase     float64
+}
+
+// newACValue excites the circuit by every source except constant ones,
+// which are usually supplies.
+func newACValue(w waveform) acValue {
+	if _, ok := w.(*dc); ok {
+		return acValue{}
+	}
+	return acValue{magnitude: 1.0}
+}
+
+func (v *acValue) phasor() complex128 {
+	return cmplx.Rect(v.magnitude, v.phase*math.Pi/180)
+}
+func (v *acValue) parameters(params map[string]float64) map[string]float64 {
+	params["AC magnitude"] = v.magnitude
+	params["AC phase"] = v.phase
+	return params
+}
+func (v *acValue) updateParameter(name string, value float64) {
+	switch name {
+	case "AC magnitude":
+		v.magnitude = value
+	case "AC phase":
+		v.phase = value
+	}
+}
+
+// linearization is the small-signal model of the circuit
+// at its operating point: G*x + C*dx/dt = b, where the excitation b
+// is given by its real and imaginary parts.
+type linearization struct {
+	conductance *mat.Dense
+	capacitance *mat.Dense
+	real        *mat.VecDense
+	imaginary   *mat.VecDense
+	// the operating point:
+	step     timeStep
+	solution *mat.VecDense
+}
+
+func (sim *simulation) linearize() (*linearization, bool) {
+	t, op, converged := sim.operatingPoint()
+	g := newSystem(len(sim.nodeVoltages), sim.branches)
+	gc := newSystem(len(sim.nodeVoltages), sim.branches)
+	re := newSystem(len(sim.nodeVoltages), sim.branches)
+	im := newSystem(len(sim.nodeVoltages), sim.branches)
+	// as at the operating point, nodes connected only through capacitors
+	// are kept from floating:
+	g.shunt(gmin)
+	gc.shunt(gmin)
+	withDerivatives := t
+	withDerivatives.dc, withDerivatives.ac = false, true
+	withDerivatives.last = op
+	for _, c := range sim.components {
+		c.stamp(sim.port(c, g, t, op))
+		c.stamp(sim.port(c, gc, withDerivatives, op))
+		if m, ok := c.Modeler.(exciter); ok {
+			m.excite(sim.port(c, re, t, op), real(m.phasor()))
+			m.excite(sim.port(c, im, t, op), imag(m.phasor()))
+		}
+	}
+	gc.matrix.Sub(gc.matrix, g.matrix)
+	return &linearization{
+		g.matrix, gc.matrix, re.currents, im.currents, t, op,
+	}, converged
+}
+
+// matrix returns the complex system at the angular frequency
+// as the real one of the double size:
+//
+//	[G -wC] [re]   [b.re]
+//	[wC  G] [im] = [b.im]
+func (l *linearization) matrix(omega float64) *mat.Dense {
+	rows, N := l.conductance.Dims()
+	a := mat.NewDense(2*rows, 2*N, nil)
+	for i := 0; i != rows; i++ {
+		for j := 0; j != N; j++ {
+			g := l.conductance.At(i, j)
+			c := omega * l.capacitance.At(i, j)
+			a.Set(i, j, g)
+			a.Set(i, N+j, -c)
+			a.Set(rows+i, j, c)
+			a.Set(rows+i, N+j, g)
+		}
+	}
+	return a
+}
+
+// solve returns the phasors of the unknowns at the angular frequency
+// and reports if the system is not singular.
+func (l *linearization) solve(omega float64) ([]complex128, bool) {
+	rows, N := l.conductance.Dims()
+	b := mat.NewVecDense(2*rows, nil)
+	for i := 0; i != rows; i++ {
+		b.SetVec(i, l.real.AtVec(i))
+		b.SetVec(rows+i, l.imaginary.AtVec(i))
+	}
+	x := mat.NewVecDense(2*N, nil)
+	err := x.SolveVec(l.matrix(omega), b)
+	res := make([]complex128, N)
+	for j := range res {
+		res[j] = complex(x.AtVec(j), x.AtVec(N+j))
+	}
+	return res, err == nil
+}
+
+// AC sweeps the frequency from start to stop in the given number of points
+// and returns magnitudes and phases in degrees of node voltages
+// of the circuit linearized at its operating point.
+func (sim *simulation) AC(start, stop float64, points int, logarithmic bool) (
+	[]float64, [][]float64, [][]float64, bool,
+) {
+	l, converged := sim.linearize()
+	frequencies := sweep(start, stop, points, logarithmic)
+	magnitudes := make([][]float64, len(sim.nodeVoltages))
+	phases := make([][]float64, len(sim.nodeVoltages))
+	for i := range magnitudes {
+		magnitudes[i] = make([]float64, len(frequencies))
+		phases[i] = make([]float64, len(frequencies))
+	}
+	for k, f := range frequencies {
+		x, solved := l.solve(2 * math.Pi * f)
+		converged = converged && solved
+		for i := range magnitudes {
+			magnitudes[i][k] = cmplx.Abs(x[i])
+			phases[i][k] = cmplx.Phase(x[i]) * 180 / math.Pi
+		}
+	}
+	return frequencies, magnitudes, phases, converged
+}
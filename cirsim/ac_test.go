@@ -0,0 +1,51 @@
+package cirsim
+
+import (
+	"math"
+	"testing"
+)
+
+func TestACOfRCFilter(t *testing.T) {
+	// the cutoff of 1k and 1uF is at 1/(2*pi*1ms):
+	sim := newTestSimulation(3,
+		settings{"voltage", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"capacitor", []int{2, 0}})
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+	sim.ModelerOfComponent(2).UpdateParameter("Capacitance", 0.000001)
+	cutoff := 1 / (2 * math.Pi * 0.001)
+	frequencies, magnitudes, phases, converged :=
+		sim.AC(cutoff/10, cutoff*10, 3, true)
+	if !converged {
+		t.Fatal("AC analysis did not converge")
+	}
+	for k, f := range frequencies {
+		w := f / cutoff
+		want := 1 / math.Sqrt(1+w*w)
+		if !near(magnitudes[2][k], want, 1e-6) {
+			t.Errorf("at %vHz: magnitude %v, want %v",
+				f, magnitudes[2][k], want)
+		}
+		phase := -math.Atan(w) * 180 / math.Pi
+		if !near(phases[2][k], phase, 1e-4) {
+			t.Errorf("at %vHz: phase %v, want %v", f, phases[2][k], phase)
+		}
+	}
+}
+
+func TestACOfCapacitiveDivider(t *testing.T) {
+	// the middle node is connected only through capacitors:
+	sim := newTestSimulation(3,
+		settings{"voltage", []int{0, 1}},
+		settings{"capacitor", []int{1, 2}},
+		settings{"capacitor", []int{2, 0}})
+	_, magnitudes, _, converged := sim.AC(10, 1000, 3, true)
+	if !converged {
+		t.Fatal("AC analysis did not converge")
+	}
+	for k, m := range magnitudes[2] {
+		if !near(m, 0.5, 1e-6) {
+			t.Errorf("point %d: magnitude %v, want 0.5", k, m)
+		}
+	}
+}
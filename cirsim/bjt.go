@@ -0,0 +1,158 @@
+package cirsim
+
+import "math"
+
+const (
+	collector = 0
+	base      = 1
+	emitter   = 2
+)
+
+// bjt is the bipolar transistor in Gummel-Poon model of the first level:
+// Ebers-Moll transport equations with the forward Early effect
+// and constant junction capacitances.
+type bjt struct {
+	polarity          float64
+	betaForward       float64
+	betaReverse       float64
+	saturationCurrent float64
+	earlyVoltage      float64
+	thermalVoltage    float64
+	baseEmitter       charge
+	baseCollector     charge
+	// limited junction voltages of the last Newton-Raphson iteration:
+	vbe float64
+	vbc float64
+}
+
+func newBJT(polarity float64) *bjt {
+	return &bjt{
+		polarity:          polarity,
+		betaForward:       100.0,
+		betaReverse:       1.0,
+		saturationCurrent: 0.00000000000001,
+		earlyVoltage:      100.0,
+		thermalVoltage:    0.025852,
+		baseEmitter:       charge{capacitance: 0.000000000001},
+		baseCollector:     charge{capacitance: 0.000000000001},
+	}
+}
+
+func (m *bjt) terminalNames() []string {
+	return []string{"c", "b", "e"}
+}
+
+func (m *bjt) stamp(p *port) {
+	vbe := m.polarity * p.voltage(base, emitter)
+	vbc := m.polarity * p.voltage(base, collector)
+	vt := m.thermalVoltage
+	m.vbe = limitJunction(vbe, m.vbe, vt, m.saturationCurrent)
+	m.vbc = limitJunction(vbc, m.vbc, vt, m.saturationCurrent)
+	p.limited = m.vbe != vbe || m.vbc != vbc
+	ic, ib, gcbe, gcbc, gbbe, gbbc := m.currents(m.vbe, m.vbc)
+	// collector and base currents both flow out through the emitter:
+	p.transconductance(collector, emitter, base, emitter, gcbe)
+	p.transconductance(collector, emitter, base, collector, gcbc)
+	p.current(collector, emitter,
+		m.polarity*(ic-gcbe*m.vbe-gcbc*m.vbc))
+	p.transconductance(base, emitter, base, emitter, gbbe)
+	p.transconductance(base, emitter, base, collector, gbbc)
+	p.current(base, emitter,
+		m.polarity*(ib-gbbe*m.vbe-gbbc*m.vbc))
+	m.baseEmitter.stamp(p, base, emitter)
+	m.baseCollector.stamp(p, base, collector)
+}
+
+func (m *bjt) terminalCurrent(p *port) float64 {
+	ic, _, _, _, _, _ := m.currents(
+		m.polarity*p.voltage(base, emitter),
+		m.polarity*p.voltage(base, collector))
+	return m.polarity*ic - m.baseCollector.flow(p, base, collector)
+}
+
+// currents returns collector and base currents of the npn transistor
+// together with their derivatives by base-emitter and base-collector
+// voltages.
+func (m *bjt) currents(
+	vbe, vbc float64,
+) (ic, ib, gcbe, gcbc, gbbe, gbbc float64) {
+	vt := m.thermalVoltage
+	is := m.saturationCurrent
+	ebe := math.Exp(vbe / vt)
+	ebc := math.Exp(vbc / vt)
+	forward := is * (ebe - 1)
+	reverse := is * (ebc - 1)
+	gForward := is*ebe/vt + gmin
+	gReverse := is*ebc/vt + gmin
+	// transport current is reduced by the base charge,
+	// which grows with the base-collector voltage because of Early effect:
+	early := 1.0
+	if m.earlyVoltage > 0 {
+		early = 1 - vbc/m.earlyVoltage
+	}
+	transport := (forward - reverse) * early
+	ic = transport - reverse/m.betaReverse
+	ib = forward/m.betaForward + reverse/m.betaReverse
+	gcbe = gForward * early
+	gcbc = -gReverse*early - gReverse/m.betaReverse
+	if m.earlyVoltage > 0 {
+		gcbc -= (forward - reverse) / m.earlyVoltage
+	}
+	gbbe = gForward / m.betaForward
+	gbbc = gReverse / m.betaReverse
+	return
+}
+
+// noise of the transistor is the shot noise of its collector
+// and base currents.
+func (m *bjt) noise(p *port, frequency float64) []noiseCurrent {
+	ic, ib, _, _, _, _ := m.currents(m.vbe, m.vbc)
+	return []noiseCurrent{
+		{collector, emitter, shotNoise(math.Abs(ic))},
+		{base, emitter, shotNoise(math.Abs(ib))},
+	}
+}
+
+func (m *bjt) truncation(p *port) float64 {
+	return math.Max(m.baseEmitter.truncation(p, base, emitter),
+		m.baseCollector.truncation(p, base, collector))
+}
+
+func (m *bjt) reset() {
+	m.baseEmitter.reset()
+	m.baseCollector.reset()
+}
+func (m *bjt) accept(p *port) {
+	m.baseEmitter.accept(p, base, emitter)
+	m.baseCollector.accept(p, base, collector)
+}
+
+func (m *bjt) Parameters() map[string]float64 {
+	return map[string]float64{
+		"Forward beta":               m.betaForward,
+		"Reverse beta":               m.betaReverse,
+		"Saturation current":         m.saturationCurrent,
+		"Early voltage":              m.earlyVoltage,
+		"Thermal voltage":            m.thermalVoltage,
+		"Base-emitter capacitance":   m.baseEmitter.capacitance,
+		"Base-collector capacitance": m.baseCollector.capacitance,
+	}
+}
+func (m *bjt) UpdateParameter(name string, value float64) {
+	switch name {
+	case "Forward beta":
+		m.betaForward = value
+	case "Reverse beta":
+		m.betaReverse = value
+	case "Saturation current":
+		m.saturationCurrent = value
+	case "Early voltage":
+		m.earlyVoltage = value
+	case "Thermal voltage":
+		m.thermalVoltage = value
+	case "Base-emitter capacitance":
+		m.baseEmitter.capacitance = value
+	case "Base-collector capacitance":
+		m.baseCollector.capacitance = value
+	}
+}
@@ -0,0 +1,84 @@
+package cirsim
+
+import (
+	"math"
+	"testing"
+)
+
+// newCommonEmitter biases the base by 10uA and feeds the collector
+// through 1k from 5V, both reversed for the pnp transistor.
+// The input comes to the base through the capacitor.
+func newCommonEmitter(model string, polarity float64) *simulation {
+	supply, bias := []int{0, 1}, []int{3, 0}
+	if polarity < 0 {
+		supply, bias = []int{1, 0}, []int{0, 3}
+	}
+	sim := newTestSimulation(5,
+		settings{"vdc", supply},
+		settings{"resistor", []int{1, 2}},
+		settings{model, []int{2, 3, 0}},
+		settings{"idc", bias},
+		settings{"voltage", []int{0, 4}},
+		settings{"capacitor", []int{4, 3}})
+	sim.ModelerOfComponent(0).UpdateParameter("DC", 5)
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+	sim.ModelerOfComponent(3).UpdateParameter("DC", 0.00001)
+	sim.ModelerOfComponent(5).UpdateParameter("Capacitance", 0.000001)
+	return sim
+}
+
+func TestBJTOperatingPoint(t *testing.T) {
+	// Vbe = Vt*ln(Ib*beta/Is+1) and Ic = beta*Ib*(1+Vcb/VA),
+	// where Vc = 5-1k*Ic; the current is exponential in Vbe,
+	// so it is only as precise as the relative tolerance:
+	for _, c := range []struct {
+		model    string
+		polarity float64
+	}{{"npn", 1}, {"pnp", -1}} {
+		sim := newCommonEmitter(c.model, c.polarity)
+		voltages, currents, converged := sim.OperatingPoint()
+		if !converged {
+			t.Fatalf("%s: operating point did not converge", c.model)
+		}
+		if vbe := c.polarity * voltages[3]; !near(vbe, 0.654791, 1e-5) {
+			t.Errorf("%s: Vbe %v, want 0.654791", c.model, vbe)
+		}
+		ic := c.polarity * currents[2]
+		if !near(ic/0.00001, 103.3121, 0.1) {
+			t.Errorf("%s: Ic/Ib %v, want 103.3121", c.model, ic/0.00001)
+		}
+		if vc := c.polarity * voltages[2]; !near(vc, 3.966879, 1e-5) {
+			t.Errorf("%s: Vc %v, want 3.966879", c.model, vc)
+		}
+		// without Early effect, the ratio is beta itself:
+		sim.ModelerOfComponent(2).UpdateParameter("Early voltage", 0)
+		_, currents, _ = sim.OperatingPoint()
+		if ic := c.polarity * currents[2]; !near(ic, 0.001, 1e-6) {
+			t.Errorf("%s: Ic %v without Early effect, want 1mA",
+				c.model, ic)
+		}
+	}
+}
+
+func TestBJTCommonEmitterGain(t *testing.T) {
+	// the gain is gm*(Rc||ro) with gm = Ic/Vt and ro = VA/(beta*Ib):
+	for _, model := range []string{"npn", "pnp"} {
+		polarity := 1.0
+		if model == "pnp" {
+			polarity = -1
+		}
+		sim := newCommonEmitter(model, polarity)
+		_, magnitudes, phases, converged := sim.AC(1000, 1000, 1, false)
+		if !converged {
+			t.Fatalf("%s: AC analysis did not converge", model)
+		}
+		gain := magnitudes[2][0] / magnitudes[3][0]
+		if !near(gain, 39.5672, 1e-3) {
+			t.Errorf("%s: gain %v, want 39.5672", model, gain)
+		}
+		shift := math.Abs(phases[2][0] - phases[3][0])
+		if !near(shift, 180, 0.1) {
+			t.Errorf("%s: phase shift %v, want 180", model, shift)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package cirsim
+
+// charge is a capacitance between two terminals of a multipole.
+// It remembers its own current for integration,
+// so the multipole has to reset and accept it as a stateful model.
+type charge struct {
+	capacitance float64
+	current     float64
+	previous    float64
+}
+
+// stamp puts i = d(C*v)/dt.
+func (q *charge) stamp(p *port, a, b int) {
+	g, history := q.integrate(p, a, b)
+	p.conductance(a, b, g*q.capacitance)
+	p.current(a, b, history)
+}
+
+// flow returns the current from a to b through the capacitance.
+func (q *charge) flow(p *port, a, b int) float64 {
+	g, history := q.integrate(p, a, b)
+	return g*q.capacitance*p.voltage(a, b) + history
+}
+
+func (q *charge) integrate(p *port, a, b int) (float64, float64) {
+	return p.integrate(q.capacitance*p.lastVoltage(a, b),
+		q.capacitance*p.previousVoltage(a, b), q.current)
+}
+
+func (q *charge) truncation(p *port, a, b int) float64 {
+	return p.truncation(q.flow(p, a, b), q.current, q.previous)
+}
+
+func (q *charge) reset() {
+	q.current = 0
+	q.previous = 0
+}
+
+func (q *charge) accept(p *port, a, b int) {
+	q.previous = q.current
+	q.current = q.flow(p, a, b)
+}
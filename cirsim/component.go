@@ -1,23 +1,75 @@
 package cirsim
 
+import "log"
+
 type ComponentSettings interface {
-	Nodes() [2]int
+	Nodes() []int
 	ModelName() string
 }
 
 type component struct {
 	Modeler
 	currentOverTime []float64
-	nodes           [2]int
+	nodes           []int
+	branch          int
+	tolerances      map[string]tolerance
 }
 
 func newComponent(settings ComponentSettings) *component {
 	var c component
 	c.nodes = settings.Nodes()
 	c.Modeler = newModeler(settings.ModelName())
-	c.currentOverTime = make([]float64, iterations)
-	for i := range c.currentOverTime {
-		c.currentOverTime[i] = 0
+	c.tolerances = make(map[string]tolerance)
+	if len(c.nodes) != len(terminalsOf(c.Modeler)) {
+		log.Fatal("wrong number of component terminals")
 	}
 	return &c
 }
+
+// Terminals returns names of the terminals of the model in the order
+// they are expected by ComponentSettings.Nodes.
+func Terminals(modelName string) []string {
+	return terminalsOf(newModeler(modelName))
+}
+
+func terminalsOf(m Modeler) []string {
+	if m, ok := m.(multipole); ok {
+		return m.terminalNames()
+	}
+	return []string{"n", "p"}
+}
+
+func (c *component) branches() int {
+	if m, ok := c.Modeler.(brancher); ok {
+		return m.branches()
+	}
+	return 0
+}
+
+func (c *component) stamp(p *port) {
+	switch m := c.Modeler.(type) {
+	case multipole:
+		m.stamp(p)
+	case bipole:
+		if l, ok := m.(limiter); ok {
+			p.limited = l.limit(p.voltage(1, 0))
+		}
+		voltage := p.voltage(1, 0)
+		p.conductance(1, 0, m.conductance(p.time, voltage))
+		p.current(1, 0, m.current(p.time, voltage))
+	}
+}
+
+// current returns the current flowing through the component
+// when its port guess is the final solution.
+func (c *component) current(p *port) float64 {
+	switch m := c.Modeler.(type) {
+	case multipole:
+		return m.terminalCurrent(p)
+	case bipole:
+		voltage := p.voltage(1, 0)
+		return voltage*m.conductance(p.time, voltage) +
+			m.current(p.time, voltage)
+	}
+	return 0
+}
@@ -0,0 +1,84 @@
+package cirsim
+
+const (
+	controlNegative = 2
+	controlPositive = 3
+)
+
+// controlled is a linear dependent source.  Its output is between
+// the first two terminals, like in other sources, and the control is
+// either the voltage between the last two terminals or the current
+// flowing through them from the positive one to the negative one.
+// Current-controlled sources do not refer to another component:
+// they sense the current as a zero-volt source, so the current
+// of another component is measured by connecting the last two terminals
+// in series with it, like the ammeter.
+type controlled struct {
+	voltageOutput  bool
+	currentControl bool
+	gain           float64
+}
+
+func newControlled(voltageOutput, currentControl bool) *controlled {
+	return &controlled{
+		voltageOutput:  voltageOutput,
+		currentControl: currentControl,
+		gain:           1.0,
+	}
+}
+
+func (m *controlled) terminalNames() []string {
+	return []string{"n", "p", "cn", "cp"}
+}
+
+func (m *controlled) branches() int {
+	branches := 0
+	if m.currentControl {
+		branches++
+	}
+	if m.voltageOutput {
+		branches++
+	}
+	return branches
+}
+
+func (m *controlled) stamp(p *port) {
+	out := 0
+	if m.currentControl {
+		p.voltageSource(controlPositive, controlNegative, 0, 0)
+		out = 1
+	}
+	switch {
+	case m.voltageOutput && m.currentControl:
+		p.voltageSource(1, 0, out, 0)
+		p.s.add(p.row(out), p.row(0), -m.gain)
+	case m.voltageOutput:
+		p.voltageSource(1, 0, out, 0)
+		p.s.add(p.row(out), p.node(controlPositive), -m.gain)
+		p.s.add(p.row(out), p.node(controlNegative), m.gain)
+	case m.currentControl:
+		p.s.add(p.node(1), p.row(0), m.gain)
+		p.s.add(p.node(0), p.row(0), -m.gain)
+	default:
+		p.transconductance(1, 0, controlPositive, controlNegative, m.gain)
+	}
+}
+
+func (m *controlled) terminalCurrent(p *port) float64 {
+	switch {
+	case m.voltageOutput:
+		return p.branchCurrent(m.branches() - 1)
+	case m.currentControl:
+		return m.gain * p.branchCurrent(0)
+	}
+	return m.gain * p.voltage(controlPositive, controlNegative)
+}
+
+func (m *controlled) Parameters() map[string]float64 {
+	return map[string]float64{"Gain": m.gain}
+}
+func (m *controlled) UpdateParameter(name string, value float64) {
+	if name == "Gain" {
+		m.gain = value
+	}
+}
@@ -0,0 +1,46 @@
+package cirsim
+
+import "testing"
+
+func TestControlledSources(t *testing.T) {
+	// 1.5V controls every source directly or by 1.5mA through 1k,
+	// which flows into the positive control terminal,
+	// while the output of 3V is loaded by 1k; as in other sources,
+	// the current is the one flowing through the source from p to n:
+	for _, c := range []struct {
+		model   string
+		nodes   []int
+		gain    float64
+		current float64
+	}{
+		{"vcvs", []int{0, 2, 0, 1}, 2, -0.003},
+		{"vccs", []int{2, 0, 0, 1}, 0.002, 0.003},
+		{"ccvs", []int{0, 2, 0, 3}, 2000, -0.003},
+		{"cccs", []int{2, 0, 0, 3}, 2, 0.003},
+	} {
+		sim := newTestSimulation(4,
+			settings{"vdc", []int{0, 1}},
+			settings{"resistor", []int{1, 3}},
+			settings{"resistor", []int{2, 0}},
+			settings{c.model, c.nodes})
+		sim.ModelerOfComponent(0).UpdateParameter("DC", 1.5)
+		sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+		sim.ModelerOfComponent(2).UpdateParameter("Resistance", 1000)
+		sim.ModelerOfComponent(3).UpdateParameter("Gain", c.gain)
+		voltages, currents, converged := sim.OperatingPoint()
+		if !converged {
+			t.Fatalf("%s: operating point did not converge", c.model)
+		}
+		if !near(voltages[2], 3, 1e-6) {
+			t.Errorf("%s: output %v, want 3", c.model, voltages[2])
+		}
+		if !near(currents[3], c.current, 1e-8) {
+			t.Errorf("%s: current %v, want %v",
+				c.model, currents[3], c.current)
+		}
+		// the current control is the ideal ammeter:
+		if c.model[0] == 'c' && !near(voltages[3], 0, 1e-9) {
+			t.Errorf("%s: control drops %v", c.model, voltages[3])
+		}
+	}
+}
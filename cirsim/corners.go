@@ -0,0 +1,116 @@
+package cirsim
+
+import (
+	"math"
+	"sort"
+)
+
+// maxExhaustive is the most parameters to try all combinations of,
+// since every one of them doubles the number of simulations.
+const maxExhaustive int = 10
+
+// Setting is the value of the parameter of the component.
+type Setting struct {
+	Component int
+	Parameter string
+	Value     float64
+}
+
+// Corners are the nominal and extreme values of the output
+// together with the settings of parameters giving the extremes.
+type Corners struct {
+	Nominal    float64
+	Low        float64
+	High       float64
+	LowCorner  []Setting
+	HighCorner []Setting
+}
+
+// WorstCase sets parameters with tolerances to their extremes and finds
+// the lowest and the highest output.  Exhaustive analysis tries all
+// combinations of extremes, otherwise every parameter is moved
+// in the direction it moves the output when changed alone.
+// The latter is also done for more than maxExhaustive parameters.
+func (sim *simulation) WorstCase(output Output, exhaustive bool) Corners {
+	nominals := sim.toleranced()
+	res := Corners{Low: math.Inf(1), High: math.Inf(-1)}
+	try := func(directions []float64) {
+		corner := sim.corner(nominals, directions)
+		sim.set(corner)
+		v := output(sim)
+		sim.set(nominals)
+		if v < res.Low {
+			res.Low, res.LowCorner = v, corner
+		}
+		if v > res.High {
+			res.High, res.HighCorner = v, corner
+		}
+	}
+	directions := make([]float64, len(nominals))
+	if exhaustive && len(nominals) <= maxExhaustive {
+		for mask := 0; mask != 1<<len(nominals); mask++ {
+			for i := range directions {
+				directions[i] = float64(mask>>i&1)*2 - 1
+			}
+			try(directions)
+		}
+	} else {
+		for i := range nominals {
+			single := make([]float64, len(nominals))
+			single[i] = 1
+			sim.set(sim.corner(nominals, single))
+			up := output(sim)
+			single[i] = -1
+			sim.set(sim.corner(nominals, single))
+			down := output(sim)
+			sim.set(nominals)
+			directions[i] = 1
+			if up < down {
+				directions[i] = -1
+			}
+		}
+		try(directions)
+		for i := range directions {
+			directions[i] = -directions[i]
+		}
+		try(directions)
+	}
+	res.Nominal = output(sim)
+	return res
+}
+
+// toleranced returns nominal settings of parameters with tolerances.
+func (sim *simulation) toleranced() []Setting {
+	var res []Setting
+	for i, c := range sim.components {
+		params := c.Parameters()
+		start := len(res)
+		for name := range c.tolerances {
+			res = append(res, Setting{i, name, params[name]})
+		}
+		sort.Slice(res[start:], func(a, b int) bool {
+			return res[start+a].Parameter < res[start+b].Parameter
+		})
+	}
+	return res
+}
+
+// corner moves every parameter to the extreme of its tolerance
+// in the given direction: up, down or, if it is zero, nowhere.
+func (sim *simulation) corner(
+	nominals []Setting, directions []float64,
+) []Setting {
+	res := make([]Setting, len(nominals))
+	for i, s := range nominals {
+		t := sim.components[s.Component].tolerances[s.Parameter]
+		s.Value *= 1 + directions[i]*t.relative
+		res[i] = s
+	}
+	return res
+}
+
+func (sim *simulation) set(settings []Setting) {
+	for _, s := range settings {
+		sim.components[s.Component].UpdateParameter(s.Parameter, s.Value)
+	}
+}
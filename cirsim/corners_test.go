@@ -0,0 +1,45 @@
+package cirsim
+
+import "testing"
+
+func TestWorstCaseOfDivider(t *testing.T) {
+	sim := newTestSimulation(3,
+		settings{"vdc", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"resistor", []int{2, 0}})
+	for i := 1; i <= 2; i++ {
+		sim.SetParameterTolerance(i, "Resistance", 0.1, Uniform)
+	}
+	for _, exhaustive := range []bool{false, true} {
+		c := sim.WorstCase(DCVoltage(2), exhaustive)
+		// the output is R2/(R1+R2):
+		if !near(c.Low, 0.45, 1e-6) || !near(c.High, 0.55, 1e-6) ||
+			!near(c.Nominal, 0.5, 1e-6) {
+			t.Errorf("exhaustive %v: %v", exhaustive, c)
+		}
+	}
+}
+
+func TestWorstCaseLimitsExhaustive(t *testing.T) {
+	// every resistor of the chain has the tolerance:
+	n := maxExhaustive + 1
+	components := []settings{{"vdc", []int{0, 1}}}
+	for i := 1; i <= n; i++ {
+		components = append(components, settings{"resistor", []int{i, i + 1}})
+	}
+	components = append(components, settings{"resistor", []int{n + 1, 0}})
+	sim := newTestSimulation(n+2, components...)
+	for i := 1; i <= n; i++ {
+		sim.SetParameterTolerance(i, "Resistance", 0.1, Uniform)
+	}
+	runs := 0
+	output := func(s Simulator) float64 {
+		runs++
+		return DCVoltage(n + 1)(s)
+	}
+	sim.WorstCase(output, true)
+	// both directions of every parameter, two corners and the nominal:
+	if runs != 2*n+3 {
+		t.Errorf("%d runs, want %d", runs, 2*n+3)
+	}
+}
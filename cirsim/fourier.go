@@ -0,0 +1,72 @@
+package cirsim
+
+import (
+	"math"
+	"math/cmplx"
+
+	"gonum.org/v1/gonum/dsp/fourier"
+)
+
+const fourierSamples = 4096
+
+// Spectrum returns the one-sided spectrum of the series, which can be
+// VoltagesOfNode or CurrentsOfComponent over Times, taken over its last
+// window seconds: amplitudes and phases in degrees of cosines.
+// The series is resampled uniformly, since time steps are variable.
+func Spectrum(times, values []float64, window float64) (
+	frequencies, magnitudes, phases []float64,
+) {
+	return spectrum(times, values, window, fourierSamples)
+}
+
+func spectrum(times, values []float64, window float64, samples int) (
+	[]float64, []float64, []float64,
+) {
+	end := times[len(times)-1]
+	at := make([]float64, samples)
+	for k := range at {
+		at[k] = end - window + window*float64(k)/float64(samples)
+	}
+	coefficients := fourier.NewFFT(samples).Coefficients(
+		nil, interpolate(times, values, at))
+	frequencies := make([]float64, len(coefficients))
+	magnitudes := make([]float64, len(coefficients))
+	phases := make([]float64, len(coefficients))
+	for k, c := range coefficients {
+		frequencies[k] = float64(k) / window
+		magnitudes[k] = 2 * cmplx.Abs(c) / float64(samples)
+		phases[k] = cmplx.Phase(c) * 180 / math.Pi
+	}
+	// the constant component is not split between positive
+	// and negative frequencies:
+	magnitudes[0] /= 2
+	return frequencies, magnitudes, phases
+}
+
+// Fourier returns amplitudes and phases in degrees of the constant
+// component and harmonics of the fundamental frequency in the series
+// over its last periods, together with the total harmonic distortion.
+func Fourier(
+	times, values []float64, fundamental float64, periods, harmonics int,
+) (magnitudes, phases []float64, thd float64) {
+	samples := fourierSamples
+	for samples <= 4*periods*harmonics {
+		samples *= 2
+	}
+	_, m, p := spectrum(times, values,
+		float64(periods)/fundamental, samples)
+	magnitudes = make([]float64, harmonics+1)
+	phases = make([]float64, harmonics+1)
+	distortion := 0.0
+	for h := range magnitudes {
+		magnitudes[h] = m[h*periods]
+		phases[h] = p[h*periods]
+		if h > 1 {
+			distortion += magnitudes[h] * magnitudes[h]
+		}
+	}
+	if harmonics > 0 {
+		thd = math.Sqrt(distortion) / magnitudes[1]
+	}
+	return magnitudes, phases, thd
+}
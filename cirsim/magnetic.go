@@ -0,0 +1,110 @@
+package cirsim
+
+import (
+	"log"
+	"math"
+)
+
+const (
+	primaryNegative   = 0
+	primaryPositive   = 1
+	secondaryNegative = 2
+	secondaryPositive = 3
+)
+
+// coupling links two inductors by the coupling coefficient,
+// adding their mutual inductance M = k*sqrt(L1*L2) to their equations:
+//
+//	v1 = L1*di1/dt + M*di2/dt
+//	v2 = M*di1/dt + L2*di2/dt
+//
+// Its terminals are not nodes, but the linked inductors.
+type coupling struct {
+	coefficient float64
+	inductors   []*component
+}
+
+func newCoupling() *coupling {
+	return &coupling{coefficient: 0.99}
+}
+
+func (m *coupling) terminalNames() []string {
+	return []string{"l1", "l2"}
+}
+
+func (m *coupling) link(components []*component) {
+	for _, c := range components {
+		if _, ok := c.Modeler.(*inductor); !ok {
+			log.Fatal("coupling links not an inductor")
+		}
+	}
+	m.inductors = components
+}
+
+// stamp adds the mutual flux to the equation of each inductor,
+// which already has its own one, so the history is split between them.
+func (m *coupling) stamp(p *port) {
+	first := m.inductors[0].Modeler.(*inductor).inductance
+	second := m.inductors[1].Modeler.(*inductor).inductance
+	mutual := m.coefficient * math.Sqrt(first*second)
+	for k, c := range m.inductors {
+		row := p.s.nodes + c.branch
+		other := p.s.nodes + m.inductors[1-k].branch
+		a, history := p.integrate(mutual*p.last.AtVec(other),
+			mutual*p.previous.AtVec(other), 0)
+		p.s.add(row, other, -a*mutual)
+		p.s.inject(row, history)
+	}
+}
+
+func (m *coupling) terminalCurrent(p *port) float64 {
+	return 0
+}
+
+func (m *coupling) Parameters() map[string]float64 {
+	return map[string]float64{"Coupling": m.coefficient}
+}
+func (m *coupling) UpdateParameter(name string, value float64) {
+	if name == "Coupling" {
+		m.coefficient = value
+	}
+}
+
+// transformer is the ideal one: v1 = n*v2 and i2 = -n*i1,
+// where n is the turns ratio and the primary current is its branch.
+type transformer struct {
+	ratio float64
+}
+
+func newTransformer() *transformer {
+	return &transformer{ratio: 1.0}
+}
+
+func (m *transformer) terminalNames() []string {
+	return []string{"n1", "p1", "n2", "p2"}
+}
+
+func (m *transformer) branches() int {
+	return 1
+}
+
+func (m *transformer) stamp(p *port) {
+	p.voltageSource(primaryPositive, primaryNegative, 0, 0)
+	p.s.add(p.row(0), p.node(secondaryPositive), -m.ratio)
+	p.s.add(p.row(0), p.node(secondaryNegative), m.ratio)
+	p.s.add(p.node(secondaryPositive), p.row(0), -m.ratio)
+	p.s.add(p.node(secondaryNegative), p.row(0), m.ratio)
+}
+
+func (m *transformer) terminalCurrent(p *port) float64 {
+	return p.branchCurrent(0)
+}
+
+func (m *transformer) Parameters() map[string]float64 {
+	return map[string]float64{"Ratio": m.ratio}
+}
+func (m *transformer) UpdateParameter(name string, value float64) {
+	if name == "Ratio" {
+		m.ratio = value
+	}
+}
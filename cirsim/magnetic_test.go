@@ -0,0 +1,73 @@
+package cirsim
+
+import (
+	"math"
+	"testing"
+)
+
+// newCoupledInductors drives the first inductor by the sine of 1kHz
+// through 1 ohm and loads the second one four times larger by 1M.
+func newCoupledInductors(coefficient float64) *simulation {
+	sim := newTestSimulation(4,
+		settings{"voltage", []int{0, 3}},
+		settings{"resistor", []int{3, 1}},
+		settings{"inductor", []int{1, 0}},
+		settings{"inductor", []int{2, 0}},
+		settings{"resistor", []int{2, 0}},
+		settings{"coupling", []int{2, 3}})
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1)
+	sim.ModelerOfComponent(2).UpdateParameter("Inductance", 0.001)
+	sim.ModelerOfComponent(3).UpdateParameter("Inductance", 0.004)
+	sim.ModelerOfComponent(4).UpdateParameter("Resistance", 1000000)
+	sim.ModelerOfComponent(5).UpdateParameter("Coupling", coefficient)
+	return sim
+}
+
+func TestCouplingInAC(t *testing.T) {
+	// the secondary voltage is M/L1 = k*sqrt(L2/L1) of the primary one:
+	for _, k := range []float64{0, 0.5, 0.9} {
+		sim := newCoupledInductors(k)
+		_, magnitudes, _, converged := sim.AC(1000, 1000, 1, false)
+		if !converged {
+			t.Fatalf("coupling %v: AC analysis did not converge", k)
+		}
+		gain := magnitudes[2][0] / magnitudes[1][0]
+		if !near(gain, 2*k, 0.001) {
+			t.Errorf("coupling %v: gain %v, want %v", k, gain, 2*k)
+		}
+	}
+}
+
+func TestCouplingInTransient(t *testing.T) {
+	// the steady state amplitude of the loaded secondary is its AC gain:
+	sim := newCoupledInductors(0.5)
+	sim.ModelerOfComponent(4).UpdateParameter("Resistance", 1000)
+	_, magnitudes, _, _ := sim.AC(1000, 1000, 1, false)
+	sim.SetSteadyState(true)
+	for m := BackwardEuler; m <= Gear; m++ {
+		sim.SetMethod(m)
+		sim.Simulate()
+		if len(sim.Unconverged()) != 0 {
+			t.Fatalf("%v: unconverged at %v", m, sim.Unconverged())
+		}
+		amplitude := 0.0
+		for _, v := range sim.VoltagesOfNode(2) {
+			amplitude = math.Max(amplitude, math.Abs(v))
+		}
+		tolerance := 0.002
+		if m == BackwardEuler {
+			tolerance = 0.02
+		}
+		if !near(amplitude, magnitudes[2][0], tolerance*amplitude) {
+			t.Errorf("%v: amplitude %v, want %v",
+				m, amplitude, magnitudes[2][0])
+		}
+	}
+}
+
+func TestCouplingIsLinked(t *testing.T) {
+	sim := newCoupledInductors(0.5)
+	if len(sim.components[5].Modeler.(*coupling).inductors) != 2 {
+		t.Error("coupling is not linked to inductors")
+	}
+}
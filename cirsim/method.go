@@ -0,0 +1,39 @@
+package cirsim
+
+// Method is the numerical integration method of the transient simulation.
+type Method int
+
+const (
+	BackwardEuler Method = iota
+	Trapezoidal
+	Gear
+)
+
+func (m Method) String() string {
+	switch m {
+	case BackwardEuler:
+		return "Backward Euler"
+	case Trapezoidal:
+		return "Trapezoidal"
+	case Gear:
+		return "Gear"
+	}
+	return "Unknown"
+}
+
+// order is the order of accuracy of the method.
+func (m Method) order() float64 {
+	if m == BackwardEuler {
+		return 1
+	}
+	return 2
+}
+
+// errorConstant is the ratio of the local truncation error
+// of the method to h^3 times the third derivative of the quantity.
+func (m Method) errorConstant() float64 {
+	if m == Gear {
+		return 2.0 / 9
+	}
+	return 1.0 / 12
+}
@@ -0,0 +1,209 @@
+package cirsim
+
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// system is the linear system of the Modified Nodal Analysis.
+// Its unknowns are node voltages followed by branch currents,
+// its equations are Kirchhoff's current law for every node
+// followed by branch equations and the one fixing the ground node.
+type system struct {
+	nodes    int
+	matrix   *mat.Dense
+	currents *mat.VecDense
+}
+
+func newSystem(nodes, branches int) *system {
+	N := nodes + branches
+	s := &system{
+		nodes:    nodes,
+		matrix:   mat.NewDense(N+1, N, nil),
+		currents: mat.NewVecDense(N+1, nil),
+	}
+	// set up additional row to determine ground node:
+	s.matrix.Set(N, 0, 1)
+	return s
+}
+
+func (s *system) add(row, col int, value float64) {
+	s.matrix.Set(row, col, s.matrix.At(row, col)+value)
+}
+
+func (s *system) inject(row int, current float64) {
+	s.currents.SetVec(row, s.currents.AtVec(row)+current)
+}
+
+// shunt connects every node to the ground by the conductance.
+func (s *system) shunt(g float64) {
+	for j := 1; j != s.nodes; j++ {
+		s.add(j, j, g)
+	}
+}
+
+// solve returns the solution and reports if it solves the system.
+// An ill-conditioned system can still be solved well, so it is checked
+// by the residual, while a singular one has only the least squares
+// solution, which misses some of the equations.  The residual is judged
+// against the right-hand side with the unit floor rather than against
+// the solution, which the least squares can blow up.
+func (s *system) solve() (*mat.VecDense, bool) {
+	_, N := s.matrix.Dims()
+	solution := mat.NewVecDense(N, nil)
+	if err := solution.SolveVec(s.matrix, s.currents); err == nil {
+		return solution, true
+	}
+	residual := mat.NewVecDense(s.currents.Len(), nil)
+	residual.MulVec(s.matrix, solution)
+	residual.SubVec(residual, s.currents)
+	scale := 1 + mat.Norm(s.currents, math.Inf(1))
+	return solution, mat.Norm(residual, math.Inf(1)) <= residualTolerance*scale
+}
+
+// timeStep is the time point being solved and the way to it.
+type timeStep struct {
+	time  float64
+	delta float64
+	// solutions at the last two time points:
+	last     *mat.VecDense
+	previous *mat.VecDense
+	// delta of the last step, zero if the history
+	// is broken by a recent discontinuity:
+	lastDelta float64
+	// set right after discontinuities:
+	restart bool
+	method  Method
+	// set for the operating point, where nothing changes over time:
+	dc bool
+	// set to find capacitances of the small-signal model,
+	// which are coefficients of derivatives of the quantities:
+	ac bool
+}
+
+// port is the system as it is seen by a single component:
+// terminals and branches are numbered in the order of the component,
+// branch holds the index of the first branch unknown of the component.
+// Besides stamping, it gives the current Newton-Raphson guess
+// and the solution of the previous time step.
+type port struct {
+	timeStep
+	s      *system
+	nodes  []int
+	branch int
+	guess  *mat.VecDense
+	// absolute tolerance of the simulation:
+	tolerance float64
+	// set by components, which had to limit their voltages:
+	limited bool
+}
+
+// ground can be used as a terminal of any component.
+const ground = -1
+
+func (p *port) node(a int) int {
+	if a == ground {
+		return 0
+	}
+	return p.nodes[a]
+}
+func (p *port) row(k int) int {
+	return p.branch + k
+}
+
+func (p *port) voltage(a, b int) float64 {
+	return p.guess.AtVec(p.node(a)) - p.guess.AtVec(p.node(b))
+}
+func (p *port) lastVoltage(a, b int) float64 {
+	return p.last.AtVec(p.node(a)) - p.last.AtVec(p.node(b))
+}
+func (p *port) previousVoltage(a, b int) float64 {
+	return p.previous.AtVec(p.node(a)) - p.previous.AtVec(p.node(b))
+}
+func (p *port) branchCurrent(k int) float64 {
+	return p.guess.AtVec(p.row(k))
+}
+func (p *port) lastBranchCurrent(k int) float64 {
+	return p.last.AtVec(p.row(k))
+}
+func (p *port) previousBranchCurrent(k int) float64 {
+	return p.previous.AtVec(p.row(k))
+}
+
+// conductance makes current g*(Va-Vb) flow from a to b.
+func (p *port) conductance(a, b int, g float64) {
+	p.transconductance(a, b, a, b, g)
+}
+
+// transconductance makes current g*(Vc-Vd) flow from a to b.
+func (p *port) transconductance(a, b, c, d int, g float64) {
+	p.s.add(p.node(a), p.node(c), g)
+	p.s.add(p.node(a), p.node(d), -g)
+	p.s.add(p.node(b), p.node(c), -g)
+	p.s.add(p.node(b), p.node(d), g)
+}
+
+// current makes the given current flow from a to b.
+func (p *port) current(a, b int, current float64) {
+	p.s.inject(p.node(a), -current)
+	p.s.inject(p.node(b), current)
+}
+
+// voltageSource makes Va-Vb equal to the voltage,
+// its current flowing from a to b is the k-th branch of the component.
+func (p *port) voltageSource(a, b, k int, voltage float64) {
+	p.s.add(p.node(a), p.row(k), 1)
+	p.s.add(p.node(b), p.row(k), -1)
+	p.s.add(p.row(k), p.node(a), 1)
+	p.s.add(p.row(k), p.node(b), -1)
+	p.s.inject(p.row(k), voltage)
+}
+
+// integrate approximates the time derivative of a quantity as a*x+b,
+// where x is the quantity at the current time, given its values
+// at the last and previous time points and its last derivative.
+// Right after discontinuities and without the history every method
+// falls back to backward Euler, which does not ring.
+// At the operating point all derivatives are zero,
+// while the small-signal model takes them as the quantities themselves.
+func (p *port) integrate(
+	last, previous, lastDerivative float64,
+) (float64, float64) {
+	switch {
+	case p.dc:
+		return 0, 0
+	case p.ac:
+		return 1, 0
+	case p.restart || p.lastDelta == 0 || p.method == BackwardEuler:
+		return 1 / p.delta, -last / p.delta
+	case p.method == Trapezoidal:
+		return 2 / p.delta, -2*last/p.delta - lastDerivative
+	}
+	// Gear's second order formula for the variable step:
+	r := p.delta / p.lastDelta
+	return (1 + 2*r) / (1 + r) / p.delta,
+		(-(1+r)*last + r*r/(1+r)*previous) / p.delta
+}
+
+// truncation estimates the local truncation error of the step
+// for a quantity, given its derivative at the current, last and previous
+// time points, and returns it relative to the allowed error.
+// The error is unknown without the history and is taken as zero.
+func (p *port) truncation(derivative, last, previous float64) float64 {
+	if p.restart || p.lastDelta == 0 {
+		return 0
+	}
+	// error of the quantity per step is compared with its derivative:
+	var err float64
+	if p.method == BackwardEuler {
+		err = (derivative - last) / 2
+	} else {
+		second := (derivative-last)/p.delta - (last-previous)/p.lastDelta
+		third := 2 * second / (p.delta + p.lastDelta)
+		err = p.delta * p.delta * p.method.errorConstant() * third
+	}
+	allowed := p.tolerance + relativeTolerance*
+		math.Max(math.Abs(derivative), math.Abs(last))
+	return math.Abs(err) / (truncationTolerance * allowed)
+}
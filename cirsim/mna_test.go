@@ -0,0 +1,49 @@
+package cirsim
+
+import "testing"
+
+func TestSolveChecksResidual(t *testing.T) {
+	// the tiny conductance is ill-conditioned, but still solvable:
+	s := newSystem(2, 0)
+	s.add(1, 1, 1e-20)
+	s.inject(1, 1e-20)
+	solution, solved := s.solve()
+	if !solved || !near(solution.AtVec(1), 1, 1e-9) {
+		t.Errorf("solution %v is solved: %v", solution.AtVec(1), solved)
+	}
+	// the current has nowhere to flow:
+	s = newSystem(2, 0)
+	s.inject(1, 1)
+	if _, solved := s.solve(); solved {
+		t.Error("singular system is solved")
+	}
+}
+
+func TestIntegrateSquare(t *testing.T) {
+	// the derivative of t^2 is 2t, which second order methods find
+	// exactly, while backward Euler is off by the step:
+	square := func(t float64) float64 { return t * t }
+	now, delta, lastDelta := 1.0, 0.1, 0.05
+	p := &port{timeStep: timeStep{
+		time: now, delta: delta, lastDelta: lastDelta,
+	}}
+	for m, want := range map[Method]float64{
+		BackwardEuler: 2*now - delta,
+		Trapezoidal:   2 * now,
+		Gear:          2 * now,
+	} {
+		p.method = m
+		a, b := p.integrate(square(now-delta),
+			square(now-delta-lastDelta), 2*(now-delta))
+		if got := a*square(now) + b; !near(got, want, 1e-9) {
+			t.Errorf("%v: derivative %v, want %v", m, got, want)
+		}
+	}
+	// right after discontinuities every method is backward Euler:
+	p.restart = true
+	p.method = Gear
+	a, b := p.integrate(square(now-delta), 0, 0)
+	if got := a*square(now) + b; !near(got, 2*now-delta, 1e-9) {
+		t.Errorf("restart: derivative %v, want %v", got, 2*now-delta)
+	}
+}
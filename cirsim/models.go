@@ -5,13 +5,107 @@ import (
 	"math"
 )
 
+const gmin float64 = 0.000000000001
+
 type Modeler interface {
-	conductance(time, delta, voltage, current float64) float64
-	current(time, delta, voltage, current float64) float64
 	Parameters() map[string]float64
 	UpdateParameter(name string, value float64)
 }
 
+// bipole is implemented by memoryless two-terminal models,
+// which current is linearized as conductance*voltage+current,
+// flowing from the second terminal to the first one.
+type bipole interface {
+	conductance(time, voltage float64) float64
+	current(time, voltage float64) float64
+}
+
+// limiter is implemented by nonlinear bipoles, which need to restrict
+// the change of their voltage between Newton-Raphson iterations.
+// The solver calls limit once per iteration before asking for conductance
+// and current, which then stay linearized around the limited voltage.
+type limiter interface {
+	limit(voltage float64) bool
+}
+
+// multipole is implemented by models with any number of terminals,
+// which stamp themselves into the system on their own.
+// The current they report is the one of their main terminal,
+// for two-terminal ones it flows in the same direction as in bipoles.
+type multipole interface {
+	terminalNames() []string
+	stamp(p *port)
+	terminalCurrent(p *port) float64
+}
+
+// stateful is implemented by models, which keep their own state
+// between time steps: it is reset before the simulation
+// and accepts the final solution of every time step.
+type stateful interface {
+	reset()
+	accept(p *port)
+}
+
+// switcher is implemented by models with discrete states.
+// The solver steps onto their scheduled breakpoints and restarts
+// integration after them or after accepted steps, which switched the state.
+type switcher interface {
+	breakpoint(after float64) float64
+	switched() bool
+}
+
+// periodic is implemented by sources, which repeat their value
+// every cycle, or return zero cycle otherwise.
+type periodic interface {
+	cycle() float64
+}
+
+// truncator is implemented by models, which integrate over time:
+// it returns the local truncation error of the solved step
+// relative to the allowed one, so the solver can choose the step.
+type truncator interface {
+	truncation(p *port) float64
+}
+
+// shaper is implemented by independent sources, which value over time
+// is given by their waveform, so it can be replaced by another one.
+type shaper interface {
+	shape() waveform
+	reshape(w waveform)
+}
+
+// exciter is implemented by independent sources, which excite the circuit
+// in the small-signal analysis with their phasor.
+type exciter interface {
+	phasor() complex128
+	excite(p *port, value float64)
+}
+
+// noisy is implemented by models, which generate noise:
+// they return noise currents between their terminals
+// at the operating point given by the port.
+type noisy interface {
+	noise(p *port, frequency float64) []noiseCurrent
+}
+
+// fixer is implemented by models with parameters, which are not values
+// to vary: parts of their structure or sentinels with special meaning.
+type fixer interface {
+	fixed(parameter string) bool
+}
+
+// linker is implemented by models, which terminals are not nodes,
+// but other components they act upon.
+type linker interface {
+	link(components []*component)
+}
+
+// brancher is implemented by multipoles, which add their own currents
+// to the unknowns of the system together with the equations for them.
+type brancher interface {
+	branches() int
+}
+
 func newModeler(name string) Modeler {
 	switch name {
 	case "resistor":
@@ -23,7 +117,41 @@ func newModeler(name string) Modeler {
 	case "diode":
 		return newDiode()
 	case "power":
-		return newPower()
+		return newPower(newSine())
+	case "voltage":
+		return newVoltage(newSine())
+	case "idc", "isin", "ipulse", "ipwl", "iexp", "isffm":
+		return newPower(newWaveform(name[1:]))
+	case "vdc", "vsin", "vpulse", "vpwl", "vexp", "vsffm":
+		return newVoltage(newWaveform(name[1:]))
+	case "npn":
+		return newBJT(1)
+	case "pnp":
+		return newBJT(-1)
+	case "nmos":
+		return newMOSFET(1)
+	case "pmos":
+		return newMOSFET(-1)
+	case "opamp":
+		return newOpamp(false)
+	case "opamp5":
+		return newOpamp(true)
+	case "vcvs":
+		return newControlled(true, false)
+	case "vccs":
+		return newControlled(false, false)
+	case "ccvs":
+		return newControlled(true, true)
+	case "cccs":
+		return newControlled(false, true)
+	case "coupling":
+		return newCoupling()
+	case "transformer":
+		return newTransformer()
+	case "switch":
+		return newTimeSwitch()
+	case "vswitch":
+		return newVoltageSwitch()
 	default:
 		log.Fatal("wrong component name")
 		// compiler wants return here, but it will be never executed:
@@ -32,18 +160,27 @@ func newModeler(name string) Modeler {
 }
 
 type capacitor struct {
-	capacitance float64
+	charge
 }
 
 func newCapacitor() *capacitor {
-	return &capacitor{0.000001}
+	return &capacitor{charge{capacitance: 0.000001}}
 }
 
-func (m *capacitor) conductance(time, delta, voltage, current float64) float64 {
-	return 2.0 * m.capacitance / delta
+func (m *capacitor) terminalNames() []string {
+	return []string{"n", "p"}
 }
-func (m *capacitor) current(time, delta, voltage, current float64) float64 {
-	return -current - voltage*2*m.capacitance/delta
+func (m *capacitor) stamp(p *port) {
+	m.charge.stamp(p, 1, 0)
+}
+func (m *capacitor) terminalCurrent(p *port) float64 {
+	return m.flow(p, 1, 0)
+}
+func (m *capacitor) truncation(p *port) float64 {
+	return m.charge.truncation(p, 1, 0)
+}
+func (m *capacitor) accept(p *port) {
+	m.charge.accept(p, 1, 0)
 }
 func (m *capacitor) Parameters() map[string]float64 {
 	return map[string]float64{"Capacitance": m.capacitance}
@@ -55,25 +192,35 @@ func (m *capacitor) UpdateParameter(name string, value float64) {
 }
 
 type resistor struct {
-	resistance float64
+	resistance  float64
+	temperature float64
 }
 
 func newResistor() *resistor {
-	return &resistor{100.0}
+	return &resistor{resistance: 100.0, temperature: roomTemperature}
 }
 
-func (m *resistor) conductance(time, delta, voltage, current float64) float64 {
+func (m *resistor) conductance(time, voltage float64) float64 {
 	return 1.0 / m.resistance
 }
-func (m *resistor) current(time, delta, voltage, current float64) float64 {
+func (m *resistor) current(time, voltage float64) float64 {
 	return 0
 }
+func (m *resistor) noise(p *port, frequency float64) []noiseCurrent {
+	return []noiseCurrent{{1, 0, thermalNoise(m.temperature, m.resistance)}}
+}
 func (m *resistor) Parameters() map[string]float64 {
-	return map[string]float64{"Resistance": m.resistance}
+	return map[string]float64{
+		"Resistance":  m.resistance,
+		"Temperature": m.temperature,
+	}
 }
 func (m *resistor) UpdateParameter(name string, value float64) {
-	if name == "Resistance" {
+	switch name {
+	case "Resistance":
 		m.resistance = value
+	case "Temperature":
+		m.temperature = value
 	}
 }
 
@@ -85,11 +232,26 @@ func newInductor() *inductor {
 	return &inductor{0.000001}
 }
 
-func (m *inductor) conductance(time, delta, voltage, current float64) float64 {
-	return delta / (2.0 * m.inductance)
+func (m *inductor) terminalNames() []string {
+	return []string{"n", "p"}
+}
+func (m *inductor) branches() int {
+	return 1
+}
+
+// stamp puts v = d(L*i)/dt, where the current is the branch.
+func (m *inductor) stamp(p *port) {
+	a, history := p.integrate(m.inductance*p.lastBranchCurrent(0),
+		m.inductance*p.previousBranchCurrent(0), p.lastVoltage(1, 0))
+	p.voltageSource(1, 0, 0, history)
+	p.s.add(p.row(0), p.row(0), -a*m.inductance)
+}
+func (m *inductor) truncation(p *port) float64 {
+	return p.truncation(
+		p.voltage(1, 0), p.lastVoltage(1, 0), p.previousVoltage(1, 0))
 }
-func (m *inductor) current(time, delta, voltage, current float64) float64 {
-	return current + voltage*delta/(2*m.inductance)
+func (m *inductor) terminalCurrent(p *port) float64 {
+	return p.branchCurrent(0)
 }
 func (m *inductor) Parameters() map[string]float64 {
 	return map[string]float64{"Inductance": m.inductance}
@@ -100,45 +262,232 @@ func (m *inductor) UpdateParameter(name string, value float64) {
 	}
 }
 
-type diode struct{}
+type diode struct {
+	saturationCurrent   float64
+	emissionCoefficient float64
+	thermalVoltage      float64
+	seriesResistance    float64
+	flickerCoefficient  float64
+	flickerExponent     float64
+	junction            float64
+}
 
 func newDiode() *diode {
-	return &diode{}
+	return &diode{
+		saturationCurrent:   0.00000000000001,
+		emissionCoefficient: 1.0,
+		thermalVoltage:      0.025852,
+		flickerExponent:     1.0,
+	}
 }
 
-func (m *diode) conductance(time, delta, voltage, current float64) float64 {
-	return 0
+func (m *diode) conductance(time, voltage float64) float64 {
+	g, _ := m.linearize()
+	return g
 }
-func (m *diode) current(time, delta, voltage, current float64) float64 {
-	return 0
+func (m *diode) current(time, voltage float64) float64 {
+	_, j := m.linearize()
+	return j
 }
 func (m *diode) Parameters() map[string]float64 {
-	return map[string]float64{}
+	return map[string]float64{
+		"Saturation current":   m.saturationCurrent,
+		"Emission coefficient": m.emissionCoefficient,
+		"Thermal voltage":      m.thermalVoltage,
+		"Series resistance":    m.seriesResistance,
+		"Flicker coefficient":  m.flickerCoefficient,
+		"Flicker exponent":     m.flickerExponent,
+	}
+}
+func (m *diode) UpdateParameter(name string, value float64) {
+	switch name {
+	case "Saturation current":
+		m.saturationCurrent = value
+	case "Emission coefficient":
+		m.emissionCoefficient = value
+	case "Thermal voltage":
+		m.thermalVoltage = value
+	case "Series resistance":
+		m.seriesResistance = value
+	case "Flicker coefficient":
+		m.flickerCoefficient = value
+	case "Flicker exponent":
+		m.flickerExponent = value
+	}
+}
+
+// noise of the diode is the shot and flicker noise of the junction
+// and the thermal noise of the series resistance at the temperature
+// given by the thermal voltage.  They are seen at the terminals
+// through the series resistance.
+func (m *diode) noise(p *port, frequency float64) []noiseCurrent {
+	vt := m.emissionCoefficient * m.thermalVoltage
+	e := math.Exp(m.junction / vt)
+	i := math.Abs(m.saturationCurrent * (e - 1))
+	g := m.saturationCurrent*e/vt + gmin
+	junction := shotNoise(i) + flickerNoise(
+		m.flickerCoefficient, m.flickerExponent, i, frequency)
+	if m.seriesResistance <= 0 {
+		return []noiseCurrent{{1, 0, junction}}
+	}
+	temperature := m.thermalVoltage*electronCharge/boltzmann - zeroCelsius
+	resistance := thermalNoise(temperature, m.seriesResistance) *
+		m.seriesResistance * m.seriesResistance
+	r := 1 / g
+	total := r + m.seriesResistance
+	density := (junction*r*r + resistance) / (total * total)
+	return []noiseCurrent{{1, 0, density}}
 }
-func (m *diode) UpdateParameter(name string, value float64) {}
 
+func (m *diode) limit(voltage float64) bool {
+	vt := m.emissionCoefficient * m.thermalVoltage
+	junction := m.junctionVoltage(voltage)
+	m.junction = limitJunction(junction, m.junction, vt, m.saturationCurrent)
+	return m.junction != junction
+}
+
+// linearize returns the conductance and the current source
+// of the diode companion model around the limited junction voltage.
+func (m *diode) linearize() (float64, float64) {
+	vt := m.emissionCoefficient * m.thermalVoltage
+	e := math.Exp(m.junction / vt)
+	i := m.saturationCurrent * (e - 1)
+	g := m.saturationCurrent * e / vt
+	v := m.junction + i*m.seriesResistance
+	g = g / (1 + g*m.seriesResistance)
+	return g + gmin, i - g*v
+}
+
+// junctionVoltage splits the voltage between the junction and the series
+// resistance by solving v = vj + Rs*Is*(exp(vj/vt) - 1) for the current.
+func (m *diode) junctionVoltage(voltage float64) float64 {
+	rs := m.seriesResistance
+	is := m.saturationCurrent
+	vt := m.emissionCoefficient * m.thermalVoltage
+	if rs <= 0 || voltage <= 0 {
+		return voltage
+	}
+	// start below the root, where Newton converges monotonically:
+	i := math.Max(0, (voltage-vt*math.Log(1+voltage/(rs*is)))/rs)
+	for k := 0; k != 100; k++ {
+		f := i*rs + vt*math.Log(1+i/is) - voltage
+		step := f / (rs + vt/(is+i))
+		i -= step
+		if math.Abs(step) <= 0.000000001*math.Abs(i) {
+			break
+		}
+	}
+	return voltage - i*rs
+}
+
+func limitJunction(voltage, last, vt, saturationCurrent float64) float64 {
+	critical := vt * math.Log(vt/(math.Sqrt2*saturationCurrent))
+	if voltage <= critical || math.Abs(voltage-last) <= 2*vt {
+		return voltage
+	}
+	if last > 0 {
+		arg := 1 + (voltage-last)/vt
+		if arg > 0 {
+			return last + vt*math.Log(arg)
+		}
+		return critical
+	}
+	return vt * math.Log(voltage/vt)
+}
+
+// power is the current source, which current flows
+// from the second terminal to the first one.
 type power struct {
-	maxCurrent float64
-	frequency  float64
+	waveform
+	acValue
 }
 
-func newPower() *power {
-	return &power{maxCurrent: 1.0, frequency: 1000.0}
+func newPower(w waveform) *power {
+	return &power{w, newACValue(w)}
 }
 
-func (m *power) conductance(time, delta, voltage, current float64) float64 {
+func (m *power) conductance(time, voltage float64) float64 {
 	return 0
 }
-func (m *power) current(time, delta, voltage, current float64) float64 {
-	return m.maxCurrent * math.Sin(time*m.frequency*2*math.Pi)
+func (m *power) current(time, voltage float64) float64 {
+	return m.value(time)
+}
+func (m *power) excite(p *port, value float64) {
+	p.current(1, 0, value)
+}
+func (m *power) switched() bool {
+	return false
+}
+func (m *power) fixed(parameter string) bool {
+	w, ok := m.waveform.(fixer)
+	return ok && w.fixed(parameter)
 }
+func (m *power) shape() waveform {
+	return m.waveform
+}
+func (m *power) reshape(w waveform) {
+	m.waveform, m.acValue = w, newACValue(w)
+}
+
+// Parameters keep the amplitude named as the current,
+// like it was named before sources got their waveforms.
 func (m *power) Parameters() map[string]float64 {
-	return map[string]float64{"Current": m.maxCurrent, "Frequency": m.frequency}
+	params := m.waveform.Parameters()
+	if amplitude, ok := params["Amplitude"]; ok {
+		delete(params, "Amplitude")
+		params["Current"] = amplitude
+	}
+	return m.acValue.parameters(params)
 }
 func (m *power) UpdateParameter(name string, value float64) {
 	if name == "Current" {
-		m.maxCurrent = value
-	} else if name == "Frequency" {
-		m.frequency = value
+		name = "Amplitude"
 	}
+	m.waveform.UpdateParameter(name, value)
+	m.acValue.updateParameter(name, value)
+}
+
+type voltage struct {
+	waveform
+	acValue
+}
+
+func newVoltage(w waveform) *voltage {
+	return &voltage{w, newACValue(w)}
+}
+
+func (m *voltage) terminalNames() []string {
+	return []string{"n", "p"}
+}
+func (m *voltage) branches() int {
+	return 1
+}
+func (m *voltage) stamp(p *port) {
+	p.voltageSource(1, 0, 0, m.value(p.time))
+}
+func (m *voltage) terminalCurrent(p *port) float64 {
+	return p.branchCurrent(0)
+}
+func (m *voltage) excite(p *port, value float64) {
+	p.s.inject(p.row(0), value)
+}
+func (m *voltage) switched() bool {
+	return false
+}
+func (m *voltage) fixed(parameter string) bool {
+	w, ok := m.waveform.(fixer)
+	return ok && w.fixed(parameter)
+}
+func (m *voltage) shape() waveform {
+	return m.waveform
+}
+func (m *voltage) reshape(w waveform) {
+	m.waveform, m.acValue = w, newACValue(w)
+}
+func (m *voltage) Parameters() map[string]float64 {
+	return m.acValue.parameters(m.waveform.Parameters())
+}
+func (m *voltage) UpdateParameter(name string, value float64) {
+	m.waveform.UpdateParameter(name, value)
+	m.acValue.updateParameter(name, value)
 }
@@ -0,0 +1,27 @@
+package cirsim
+
+import "testing"
+
+func TestDiodeFollowsShockley(t *testing.T) {
+	// V = nVt*ln(I/Is+1) = 0.025852*ln(1e-3/1e-14+1) for 1mA:
+	sim := newTestSimulation(2,
+		settings{"idc", []int{0, 1}},
+		settings{"diode", []int{1, 0}})
+	sim.ModelerOfComponent(0).UpdateParameter("DC", 0.001)
+	voltages, currents, converged := sim.OperatingPoint()
+	if !converged {
+		t.Fatal("operating point did not converge")
+	}
+	if !near(voltages[1], -0.654791, 1e-5) {
+		t.Errorf("diode voltage %v, want -0.654791", voltages[1])
+	}
+	if !near(currents[1], 0.001, 1e-9) {
+		t.Errorf("diode current %v, want 1mA", currents[1])
+	}
+	// the emission coefficient scales the voltage:
+	sim.ModelerOfComponent(1).UpdateParameter("Emission coefficient", 2)
+	voltages, _, _ = sim.OperatingPoint()
+	if !near(voltages[1], -2*0.654791, 2e-5) {
+		t.Errorf("diode voltage %v, want %v", voltages[1], -2*0.654791)
+	}
+}
@@ -0,0 +1,206 @@
+package cirsim
+
+import (
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Distribution is the distribution of the parameter within its tolerance.
+type Distribution int
+
+const (
+	Uniform Distribution = iota
+	// Gaussian takes the tolerance as three standard deviations:
+	Gaussian
+)
+
+type tolerance struct {
+	relative     float64
+	distribution Distribution
+}
+
+// Envelope is the spread of a quantity over Monte Carlo runs,
+// its deviation is the sample standard deviation, which is zero
+// for a single run.
+type Envelope struct {
+	Min       []float64
+	Max       []float64
+	Mean      []float64
+	Deviation []float64
+}
+
+func (sim *simulation) ParameterTolerance(
+	component int, parameter string,
+) (float64, Distribution) {
+	t := sim.components[component].tolerances[parameter]
+	return t.relative, t.distribution
+}
+
+// SetParameterTolerance sets the relative tolerance of the parameter,
+// zero tolerance makes the parameter nominal.
+func (sim *simulation) SetParameterTolerance(
+	component int, parameter string,
+	relative float64, distribution Distribution,
+) {
+	c := sim.components[component]
+	if _, ok := c.Parameters()[parameter]; !ok {
+		log.Fatal("wrong parameter name: ", parameter)
+	}
+	if relative == 0 {
+		delete(c.tolerances, parameter)
+	} else {
+		c.tolerances[parameter] = tolerance{relative, distribution}
+	}
+}
+
+// MonteCarlo runs the transient simulation with parameters randomized
+// within their tolerances and returns envelopes of node voltages
+// at time points of the nominal simulation, which is run afterwards.
+func (sim *simulation) MonteCarlo(
+	runs int, seed int64,
+) ([]float64, []Envelope) {
+	sim.Simulate()
+	times := sim.times
+	envelopes := sim.monteCarlo(runs, seed, func() [][]float64 {
+		sim.Simulate()
+		res := make([][]float64, len(sim.nodeVoltages))
+		for i, n := range sim.nodeVoltages {
+			res[i] = interpolate(sim.times, n, times)
+		}
+		return res
+	})
+	sim.Simulate()
+	return times, envelopes
+}
+
+// MonteCarloAC runs the AC analysis with parameters randomized
+// within their tolerances and returns envelopes of magnitudes
+// of node voltages.
+func (sim *simulation) MonteCarloAC(
+	runs int, seed int64,
+	start, stop float64, points int, logarithmic bool,
+) ([]float64, []Envelope, bool) {
+	converged := true
+	frequencies := sweep(start, stop, points, logarithmic)
+	envelopes := sim.monteCarlo(runs, seed, func() [][]float64 {
+		_, magnitudes, _, ok := sim.AC(start, stop, points, logarithmic)
+		converged = converged && ok
+		return magnitudes
+	})
+	return frequencies, envelopes, converged
+}
+
+// monteCarlo gathers envelopes of quantities of every node
+// given by the analysis of randomized circuits.
+func (sim *simulation) monteCarlo(
+	runs int, seed int64, analyze func() [][]float64,
+) []Envelope {
+	random := rand.New(rand.NewSource(seed))
+	var envelopes []Envelope
+	for run := 0; run != runs; run++ {
+		restore := sim.randomize(random)
+		quantities := analyze()
+		restore()
+		if envelopes == nil {
+			envelopes = make([]Envelope, len(quantities))
+			for i, q := range quantities {
+				envelopes[i] = newEnvelope(len(q))
+			}
+		}
+		for i, q := range quantities {
+			envelopes[i].add(q, run+1)
+		}
+	}
+	for i := range envelopes {
+		envelopes[i].finish(runs)
+	}
+	return envelopes
+}
+
+// randomize sets random values to parameters with tolerances
+// and returns the function restoring the nominal ones.
+func (sim *simulation) randomize(random *rand.Rand) func() {
+	var restore []func()
+	for _, c := range sim.components {
+		params := c.Parameters()
+		// iterate in the fixed order to keep runs reproducible:
+		names := make([]string, 0, len(c.tolerances))
+		for name := range c.tolerances {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			t := c.tolerances[name]
+			deviation := t.relative * (2*random.Float64() - 1)
+			if t.distribution == Gaussian {
+				deviation = t.relative / 3 * random.NormFloat64()
+			}
+			c, name, nominal := c, name, params[name]
+			c.UpdateParameter(name, nominal*(1+deviation))
+			restore = append(restore, func() {
+				c.UpdateParameter(name, nominal)
+			})
+		}
+	}
+	return func() {
+		for _, r := range restore {
+			r()
+		}
+	}
+}
+
+func newEnvelope(points int) Envelope {
+	e := Envelope{
+		Min:       make([]float64, points),
+		Max:       make([]float64, points),
+		Mean:      make([]float64, points),
+		Deviation: make([]float64, points),
+	}
+	for k := range e.Min {
+		e.Min[k] = math.Inf(1)
+		e.Max[k] = math.Inf(-1)
+	}
+	return e
+}
+
+// add accumulates the n-th run by Welford's method,
+// keeping the sum of squared deviations in Deviation until the finish.
+func (e *Envelope) add(values []float64, n int) {
+	for k, v := range values {
+		e.Min[k] = math.Min(e.Min[k], v)
+		e.Max[k] = math.Max(e.Max[k], v)
+		delta := v - e.Mean[k]
+		e.Mean[k] += delta / float64(n)
+		e.Deviation[k] += delta * (v - e.Mean[k])
+	}
+}
+
+func (e *Envelope) finish(runs int) {
+	for k := range e.Deviation {
+		if runs < 2 {
+			e.Deviation[k] = 0
+		} else {
+			e.Deviation[k] = math.Sqrt(e.Deviation[k] / float64(runs-1))
+		}
+	}
+}
+
+// interpolate returns values linearly interpolated at the given times.
+func interpolate(times, values, at []float64) []float64 {
+	res := make([]float64, len(at))
+	for k, t := range at {
+		i := sort.SearchFloat64s(times, t)
+		switch {
+		case i == len(times):
+			res[k] = values[len(values)-1]
+		case i == 0 || times[i] == t:
+			res[k] = values[i]
+		default:
+			f := (t - times[i-1]) / (times[i] - times[i-1])
+			res[k] = values[i-1] + f*(values[i]-values[i-1])
+		}
+	}
+	return res
+}
@@ -0,0 +1,89 @@
+package cirsim
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestEnvelopeOfSample(t *testing.T) {
+	// 1, 2, 3 and 4 have the mean of 2.5 and the sample variance of 5/3:
+	e := newEnvelope(1)
+	for n, v := range []float64{3, 1, 4, 2} {
+		e.add([]float64{v}, n+1)
+	}
+	e.finish(4)
+	if e.Min[0] != 1 || e.Max[0] != 4 || !near(e.Mean[0], 2.5, 1e-12) {
+		t.Errorf("min %v, max %v, mean %v", e.Min[0], e.Max[0], e.Mean[0])
+	}
+	if want := math.Sqrt(5.0 / 3); !near(e.Deviation[0], want, 1e-12) {
+		t.Errorf("deviation %v, want %v", e.Deviation[0], want)
+	}
+	single := newEnvelope(1)
+	single.add([]float64{3}, 1)
+	single.finish(1)
+	if single.Deviation[0] != 0 {
+		t.Errorf("deviation of the single run %v", single.Deviation[0])
+	}
+}
+
+func TestMonteCarloIsReproducible(t *testing.T) {
+	// the RC filter with 10% capacitors spreads around its cutoff:
+	sim := newTestSimulation(3,
+		settings{"voltage", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"capacitor", []int{2, 0}})
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+	sim.ModelerOfComponent(2).UpdateParameter("Capacitance", 0.000001)
+	sim.SetParameterTolerance(2, "Capacitance", 0.1, Gaussian)
+	cutoff := 1 / (2 * math.Pi * 0.001)
+	_, first, converged := sim.MonteCarloAC(20, 1, cutoff, cutoff, 1, false)
+	if !converged {
+		t.Fatal("AC analysis did not converge")
+	}
+	_, again, _ := sim.MonteCarloAC(20, 1, cutoff, cutoff, 1, false)
+	if !reflect.DeepEqual(first, again) {
+		t.Error("the same seed gives different envelopes")
+	}
+	_, other, _ := sim.MonteCarloAC(20, 2, cutoff, cutoff, 1, false)
+	if reflect.DeepEqual(first, other) {
+		t.Error("different seeds give the same envelopes")
+	}
+	e := first[2]
+	if !(e.Min[0] <= e.Mean[0] && e.Mean[0] <= e.Max[0]) ||
+		e.Min[0] == e.Max[0] || e.Deviation[0] <= 0 {
+		t.Errorf("envelope %v", e)
+	}
+	// the nominal magnitude is 1/sqrt(2), and capacitances within 10%
+	// shift it by less than 5%:
+	if e.Min[0] < 0.95/math.Sqrt2 || e.Max[0] > 1.05/math.Sqrt2 {
+		t.Errorf("magnitudes from %v to %v", e.Min[0], e.Max[0])
+	}
+	c := sim.ModelerOfComponent(2).Parameters()["Capacitance"]
+	if c != 0.000001 {
+		t.Errorf("capacitance is left at %v", c)
+	}
+}
+
+func TestMonteCarloOfTransient(t *testing.T) {
+	sim := newTestSimulation(3,
+		settings{"vdc", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"resistor", []int{2, 0}})
+	sim.ModelerOfComponent(0).UpdateParameter("DC", 1)
+	sim.SetParameterTolerance(1, "Resistance", 0.1, Uniform)
+	times, envelopes := sim.MonteCarlo(10, 1)
+	if len(envelopes) != 3 || len(envelopes[2].Mean) != len(times) {
+		t.Fatalf("%d envelopes over %d times", len(envelopes), len(times))
+	}
+	// the upper resistor from 0.9 to 1.1 of the lower one
+	// leaves from 1/2.1 to 1/1.9 of the source:
+	e := envelopes[2]
+	for k := range times {
+		if !(e.Min[k] <= e.Mean[k] && e.Mean[k] <= e.Max[k]) ||
+			e.Min[k] < 1/2.1 || e.Max[k] > 1/1.9 {
+			t.Fatalf("at %vs: from %v through %v to %v",
+				times[k], e.Min[k], e.Mean[k], e.Max[k])
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package cirsim
+
+import "math"
+
+const (
+	drain  = 0
+	gate   = 1
+	source = 2
+	bulk   = 3
+)
+
+// mosfet is the field-effect transistor in Shichman-Hodges model
+// with the body effect and constant gate capacitances.
+type mosfet struct {
+	polarity         float64
+	threshold        float64
+	transconductance float64
+	lambda           float64
+	gamma            float64
+	phi              float64
+	gateSource       charge
+	gateDrain        charge
+}
+
+func newMOSFET(polarity float64) *mosfet {
+	return &mosfet{
+		polarity:         polarity,
+		threshold:        1.0,
+		transconductance: 0.001,
+		lambda:           0.02,
+		phi:              0.6,
+		gateSource:       charge{capacitance: 0.000000000001},
+		gateDrain:        charge{capacitance: 0.000000000001},
+	}
+}
+
+func (m *mosfet) terminalNames() []string {
+	return []string{"d", "g", "s", "b"}
+}
+
+func (m *mosfet) stamp(p *port) {
+	d, s := m.orientation(p)
+	vgs := m.polarity * p.voltage(gate, s)
+	vds := m.polarity * p.voltage(d, s)
+	vbs := m.polarity * p.voltage(bulk, s)
+	id, gm, gds, gmb := m.drainCurrent(vgs, vds, vbs)
+	p.transconductance(d, s, gate, s, gm)
+	p.conductance(d, s, gds)
+	p.transconductance(d, s, bulk, s, gmb)
+	p.current(d, s, m.polarity*(id-gm*vgs-gds*vds-gmb*vbs))
+	p.conductance(drain, source, gmin)
+	m.gateSource.stamp(p, gate, source)
+	m.gateDrain.stamp(p, gate, drain)
+}
+
+func (m *mosfet) terminalCurrent(p *port) float64 {
+	d, s := m.orientation(p)
+	id, _, _, _ := m.drainCurrent(
+		m.polarity*p.voltage(gate, s),
+		m.polarity*p.voltage(d, s),
+		m.polarity*p.voltage(bulk, s))
+	if d != drain {
+		id = -id
+	}
+	return m.polarity*id - m.gateDrain.flow(p, gate, drain)
+}
+
+// orientation returns the terminals acting as drain and source:
+// the channel is symmetric, so they swap when the drain voltage
+// falls below the source one.
+func (m *mosfet) orientation(p *port) (int, int) {
+	if m.polarity*p.voltage(drain, source) < 0 {
+		return source, drain
+	}
+	return drain, source
+}
+
+// drainCurrent returns the drain current of the n-channel transistor
+// together with its derivatives by gate, drain and bulk voltages.
+func (m *mosfet) drainCurrent(
+	vgs, vds, vbs float64,
+) (id, gm, gds, gmb float64) {
+	// body effect rises the threshold when bulk is below the source:
+	surface := math.Sqrt(math.Max(m.phi-vbs, 0))
+	vth := m.threshold + m.gamma*(surface-math.Sqrt(m.phi))
+	overdrive := vgs - vth
+	if overdrive <= 0 {
+		return 0, 0, 0, 0
+	}
+	k := m.transconductance
+	modulation := 1 + m.lambda*vds
+	if vds < overdrive {
+		// linear region:
+		id = k * (overdrive - vds/2) * vds * modulation
+		gm = k * vds * modulation
+		gds = k*(overdrive-vds)*modulation +
+			k*(overdrive-vds/2)*vds*m.lambda
+	} else {
+		// saturation:
+		id = k / 2 * overdrive * overdrive * modulation
+		gm = k * overdrive * modulation
+		gds = k / 2 * overdrive * overdrive * m.lambda
+	}
+	if surface > 0 {
+		gmb = gm * m.gamma / (2 * surface)
+	}
+	return id, gm, gds, gmb
+}
+
+func (m *mosfet) truncation(p *port) float64 {
+	return math.Max(m.gateSource.truncation(p, gate, source),
+		m.gateDrain.truncation(p, gate, drain))
+}
+
+func (m *mosfet) reset() {
+	m.gateSource.reset()
+	m.gateDrain.reset()
+}
+func (m *mosfet) accept(p *port) {
+	m.gateSource.accept(p, gate, source)
+	m.gateDrain.accept(p, gate, drain)
+}
+
+func (m *mosfet) Parameters() map[string]float64 {
+	return map[string]float64{
+		"Threshold voltage":       m.threshold,
+		"Transconductance":        m.transconductance,
+		"Lambda":                  m.lambda,
+		"Gamma":                   m.gamma,
+		"Phi":                     m.phi,
+		"Gate-source capacitance": m.gateSource.capacitance,
+		"Gate-drain capacitance":  m.gateDrain.capacitance,
+	}
+}
+func (m *mosfet) UpdateParameter(name string, value float64) {
+	switch name {
+	case "Threshold voltage":
+		m.threshold = value
+	case "Transconductance":
+		m.transconductance = value
+	case "Lambda":
+		m.lambda = value
+	case "Gamma":
+		m.gamma = value
+	case "Phi":
+		m.phi = value
+	case "Gate-source capacitance":
+		m.gateSource.capacitance = value
+	case "Gate-drain capacitance":
+		m.gateDrain.capacitance = value
+	}
+}
@@ -0,0 +1,81 @@
+package cirsim
+
+import (
+	"math"
+	"testing"
+)
+
+// newInverter is the CMOS inverter supplied by 5V with transistors
+// of the same size, their drains and sources can be swapped.
+func newInverter(swapped bool) *simulation {
+	n, p := []int{3, 2, 0, 0}, []int{3, 2, 1, 1}
+	if swapped {
+		n, p = []int{0, 2, 3, 0}, []int{1, 2, 3, 1}
+	}
+	sim := newTestSimulation(4,
+		settings{"vdc", []int{0, 1}},
+		settings{"vdc", []int{0, 2}},
+		settings{"nmos", n},
+		settings{"pmos", p})
+	sim.ModelerOfComponent(0).UpdateParameter("DC", 5)
+	return sim
+}
+
+func TestInverterOperatingPoint(t *testing.T) {
+	// in the middle both transistors are saturated with the same
+	// current k/2*(2.5-1)^2*(1+2.5*lambda):
+	for _, swapped := range []bool{false, true} {
+		sim := newInverter(swapped)
+		for _, c := range []struct{ in, out, current float64 }{
+			{0, 5, 0},
+			{2.5, 2.5, 0.00118125},
+			{5, 0, 0},
+		} {
+			sim.ModelerOfComponent(1).UpdateParameter("DC", c.in)
+			voltages, currents, converged := sim.OperatingPoint()
+			if !converged {
+				t.Fatalf("swapped %v, input %v: did not converge",
+					swapped, c.in)
+			}
+			if !near(voltages[3], c.out, 1e-6) {
+				t.Errorf("swapped %v, input %v: output %v, want %v",
+					swapped, c.in, voltages[3], c.out)
+			}
+			if !near(math.Abs(currents[0]), c.current, 1e-9) ||
+				!near(math.Abs(currents[2]), c.current, 1e-9) {
+				t.Errorf("swapped %v, input %v: currents %v and %v, want %v",
+					swapped, c.in, currents[0], currents[2], c.current)
+			}
+		}
+	}
+}
+
+func TestMOSFETBodyEffect(t *testing.T) {
+	// the follower sinking 100uA with the bulk grounded
+	// has Vs = 5-Vth-sqrt(2*100uA/k) with
+	// Vth = 1+gamma*(sqrt(phi+Vs)-sqrt(phi)), mirrored for pmos:
+	for _, c := range []struct {
+		model          string
+		supply, source []int
+		polarity       float64
+	}{
+		{"nmos", []int{0, 1}, []int{0, 2}, 1},
+		{"pmos", []int{1, 0}, []int{2, 0}, -1},
+	} {
+		sim := newTestSimulation(3,
+			settings{"vdc", c.supply},
+			settings{c.model, []int{1, 1, 2, 0}},
+			settings{"idc", c.source})
+		sim.ModelerOfComponent(0).UpdateParameter("DC", 5)
+		sim.ModelerOfComponent(1).UpdateParameter("Lambda", 0)
+		sim.ModelerOfComponent(1).UpdateParameter("Gamma", 0.5)
+		sim.ModelerOfComponent(2).UpdateParameter("DC", 0.0001)
+		voltages, _, converged := sim.OperatingPoint()
+		if !converged {
+			t.Fatalf("%s: operating point did not converge", c.model)
+		}
+		if vs := c.polarity * voltages[2]; !near(vs, 2.992403, 1e-5) {
+			t.Errorf("%s: source at %v, want 2.992403", c.model, vs)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package cirsim
+
+import (
+	"math"
+	"math/cmplx"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+const (
+	boltzmann       float64 = 1.380649e-23
+	electronCharge  float64 = 1.602176634e-19
+	zeroCelsius     float64 = 273.15
+	roomTemperature float64 = 27
+)
+
+// noiseCurrent is the noise current source between terminals a and b
+// with the power spectral density in A^2/Hz.
+type noiseCurrent struct {
+	a, b    int
+	density float64
+}
+
+// thermalNoise returns the density of the current noise of the resistance
+// at the temperature in degrees Celsius.
+func thermalNoise(temperature, resistance float64) float64 {
+	return 4 * boltzmann * (temperature + zeroCelsius) / resistance
+}
+
+func shotNoise(current float64) float64 {
+	return 2 * electronCharge * current
+}
+
+func flickerNoise(coefficient, exponent, current, frequency float64) float64 {
+	if coefficient == 0 {
+		return 0
+	}
+	return coefficient * math.Pow(current, exponent) / frequency
+}
+
+// Noise sweeps the frequency like AC and returns the noise density
+// of the node voltage in V/sqrt(Hz) together with the input-referred one:
+// the output noise divided by the gain from the AC excitation.
+// The input-referred noise is nil, if nothing excites the circuit.
+func (sim *simulation) Noise(
+	node int, start, stop float64, points int, logarithmic bool,
+) ([]float64, []float64, []float64, bool) {
+	l, converged := sim.linearize()
+	frequencies := sweep(start, stop, points, logarithmic)
+	output := make([]float64, len(frequencies))
+	var input []float64
+	if mat.Norm(l.real, 2) != 0 || mat.Norm(l.imaginary, 2) != 0 {
+		input = make([]float64, len(frequencies))
+	}
+	rows, N := l.conductance.Dims()
+	for k, f := range frequencies {
+		var densities []float64
+		var injections [][2]int
+		for _, c := range sim.components {
+			m, ok := c.Modeler.(noisy)
+			if !ok {
+				continue
+			}
+			p := sim.port(c, nil, l.step, l.solution)
+			for _, n := range m.noise(p, f) {
+				densities = append(densities, n.density)
+				injections = append(injections, [2]int{p.node(n.a), p.node(n.b)})
+			}
+		}
+		// the first column is the excitation, others are unit noise currents:
+		b := mat.NewDense(2*rows, 1+len(densities), nil)
+		for i := 0; i != rows; i++ {
+			b.Set(i, 0, l.real.AtVec(i))
+			b.Set(rows+i, 0, l.imaginary.AtVec(i))
+		}
+		for j, in := range injections {
+			b.Set(in[0], 1+j, b.At(in[0], 1+j)-1)
+			b.Set(in[1], 1+j, b.At(in[1], 1+j)+1)
+		}
+		var x mat.Dense
+		if err := x.Solve(l.matrix(2*math.Pi*f), b); err != nil {
+			converged = false
+		}
+		density := 0.0
+		for j, d := range densities {
+			transfer := complex(x.At(node, 1+j), x.At(N+node, 1+j))
+			density += d * real(transfer*cmplx.Conj(transfer))
+		}
+		output[k] = math.Sqrt(density)
+		if input != nil {
+			gain := cmplx.Abs(complex(x.At(node, 0), x.At(N+node, 0)))
+			input[k] = output[k] / gain
+		}
+	}
+	return frequencies, output, input, converged
+}
@@ -0,0 +1,40 @@
+package cirsim
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNoiseOfDivider(t *testing.T) {
+	// both 1k resistors drive their noise currents into 500 ohms:
+	want := math.Sqrt(2 * thermalNoise(roomTemperature, 1000) * 500 * 500)
+	for _, source := range []string{"vdc", "voltage"} {
+		sim := newTestSimulation(3,
+			settings{source, []int{0, 1}},
+			settings{"resistor", []int{1, 2}},
+			settings{"resistor", []int{2, 0}})
+		sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+		sim.ModelerOfComponent(2).UpdateParameter("Resistance", 1000)
+		_, output, input, converged := sim.Noise(2, 10, 1000, 3, true)
+		if !converged {
+			t.Fatalf("%s: noise analysis did not converge", source)
+		}
+		for k, n := range output {
+			if !near(n, want, 1e-6*want) {
+				t.Errorf("%s, point %d: noise %v, want %v", source, k, n, want)
+			}
+		}
+		if source == "vdc" {
+			// the constant source is not an AC excitation:
+			if input != nil {
+				t.Errorf("input noise %v without excitation", input)
+			}
+			continue
+		}
+		for k, n := range input {
+			if !near(n, 2*want, 1e-6*want) {
+				t.Errorf("point %d: input noise %v, want %v", k, n, 2*want)
+			}
+		}
+	}
+}
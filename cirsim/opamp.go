@@ -0,0 +1,165 @@
+package cirsim
+
+import "math"
+
+const (
+	inverting    = 0
+	nonInverting = 1
+	output       = 2
+	positive     = 3
+	negative     = 4
+)
+
+// opamp is the operational amplifier with finite gain, single pole
+// and output clamped between the rails.  Rails are its parameters or,
+// if the opamp is supplied, voltages of its supply terminals.
+// Zero gain makes it ideal: without the pole and the clamping.
+// It has two branches: the first one is the open-loop output voltage
+// behind the pole, the second one is the current of the output terminal.
+type opamp struct {
+	supplied         bool
+	gain             float64
+	inputResistance  float64
+	outputResistance float64
+	bandwidth        float64
+	positiveRail     float64
+	negativeRail     float64
+}
+
+func newOpamp(supplied bool) *opamp {
+	return &opamp{
+		supplied:         supplied,
+		gain:             100000.0,
+		inputResistance:  1000000.0,
+		outputResistance: 10.0,
+		bandwidth:        10.0,
+		positiveRail:     15.0,
+		negativeRail:     -15.0,
+	}
+}
+
+func (m *opamp) terminalNames() []string {
+	if m.supplied {
+		return []string{"minus", "plus", "out", "vcc", "vee"}
+	}
+	return []string{"minus", "plus", "out"}
+}
+
+func (m *opamp) branches() int {
+	return 2
+}
+
+func (m *opamp) stamp(p *port) {
+	p.conductance(nonInverting, inverting, 1/m.inputResistance)
+	row := p.row(0)
+	if m.gain <= 0 {
+		// ideal opamp keeps its inputs equal whatever the output is:
+		p.s.add(row, p.node(nonInverting), 1)
+		p.s.add(row, p.node(inverting), -1)
+	} else {
+		m.stampPole(p, row)
+	}
+	// Vout - Rout*I = x, where I flows into the output terminal
+	// and leaves through the negative rail:
+	out := p.row(1)
+	rail := ground
+	if m.supplied {
+		rail = negative
+	}
+	p.s.add(p.node(output), out, 1)
+	p.s.add(p.node(rail), out, -1)
+	p.s.add(out, p.node(output), 1)
+	p.s.add(out, out, -m.outputResistance)
+	p.s.add(out, row, -1)
+}
+
+// stampPole puts tau*dx/dt + x = gain*vd, or just x = gain*vd without
+// the bandwidth, as self*x - gain*vd = history.  The open-loop output x
+// is held at the rail it tries to cross, so the opamp leaves saturation
+// without delay.
+func (m *opamp) stampPole(p *port, row int) {
+	self, history := 1.0, 0.0
+	if m.bandwidth > 0 {
+		tau := 1 / (2 * math.Pi * m.bandwidth)
+		last := p.lastBranchCurrent(0)
+		derivative := (m.gain*p.lastVoltage(nonInverting, inverting) - last) /
+			tau
+		high, low := m.rails(p.lastVoltage)
+		if last >= high && derivative > 0 || last <= low && derivative < 0 {
+			derivative = 0
+		}
+		a, b := p.integrate(last, p.previousBranchCurrent(0), derivative)
+		self, history = 1+tau*a, -tau*b
+	}
+	free := (history + m.gain*p.voltage(nonInverting, inverting)) / self
+	if p.ac {
+		// the small-signal model keeps the region of the operating point:
+		free = m.gain * p.voltage(nonInverting, inverting)
+	}
+	high, low := m.rails(p.voltage)
+	switch {
+	case free > high:
+		m.stampRail(p, row, positive, m.positiveRail)
+	case free < low:
+		m.stampRail(p, row, negative, m.negativeRail)
+	default:
+		p.s.add(row, row, self)
+		p.s.add(row, p.node(nonInverting), -m.gain)
+		p.s.add(row, p.node(inverting), m.gain)
+		p.s.inject(row, history)
+	}
+}
+
+func (m *opamp) rails(voltage func(a, b int) float64) (float64, float64) {
+	if m.supplied {
+		return voltage(positive, ground), voltage(negative, ground)
+	}
+	return m.positiveRail, m.negativeRail
+}
+
+func (m *opamp) stampRail(p *port, row, terminal int, voltage float64) {
+	p.s.add(row, row, 1)
+	if m.supplied {
+		p.s.add(row, p.node(terminal), -1)
+	} else {
+		p.s.inject(row, voltage)
+	}
+}
+
+func (m *opamp) terminalCurrent(p *port) float64 {
+	return -p.branchCurrent(1)
+}
+
+func (m *opamp) Parameters() map[string]float64 {
+	params := map[string]float64{
+		"Gain":              m.gain,
+		"Input resistance":  m.inputResistance,
+		"Output resistance": m.outputResistance,
+		"Bandwidth":         m.bandwidth,
+	}
+	if !m.supplied {
+		params["Positive rail"] = m.positiveRail
+		params["Negative rail"] = m.negativeRail
+	}
+	return params
+}
+func (m *opamp) fixed(parameter string) bool {
+	// zero gain means the ideal op-amp, not the one without gain:
+	return parameter == "Gain" && m.gain <= 0
+}
+func (m *opamp) UpdateParameter(name string, value float64) {
+	switch name {
+	case "Gain":
+		m.gain = value
+	case "Input resistance":
+		m.inputResistance = value
+	case "Output resistance":
+		m.outputResistance = value
+	case "Bandwidth":
+		m.bandwidth = value
+	case "Positive rail":
+		m.positiveRail = value
+	case "Negative rail":
+		m.negativeRail = value
+	}
+}
@@ -0,0 +1,54 @@
+package cirsim
+
+import "testing"
+
+func TestOpampSaturatesAtRails(t *testing.T) {
+	// the comparator loaded by 10k has the rail divided by Rout and 10k;
+	// the supplied one takes its rails from 12V and -5V:
+	for _, c := range []struct {
+		model     string
+		nodes     []int
+		high, low float64
+	}{
+		{"opamp", []int{0, 1, 2}, 15, -15},
+		{"opamp5", []int{0, 1, 2, 3, 4}, 12, -5},
+	} {
+		sim := newTestSimulation(5,
+			settings{"vdc", []int{0, 1}},
+			settings{"resistor", []int{2, 0}},
+			settings{"vdc", []int{0, 3}},
+			settings{"vdc", []int{0, 4}},
+			settings{c.model, c.nodes})
+		sim.ModelerOfComponent(1).UpdateParameter("Resistance", 10000)
+		sim.ModelerOfComponent(2).UpdateParameter("DC", 12)
+		sim.ModelerOfComponent(3).UpdateParameter("DC", -5)
+		for _, input := range []float64{1, -1} {
+			sim.ModelerOfComponent(0).UpdateParameter("DC", input)
+			want := c.high
+			if input < 0 {
+				want = c.low
+			}
+			want *= 10000.0 / 10010
+			voltages, _, converged := sim.OperatingPoint()
+			if !converged {
+				t.Fatalf("%s, %v: did not converge", c.model, input)
+			}
+			if !near(voltages[2], want, 1e-6) {
+				t.Errorf("%s, %v: output %v, want %v",
+					c.model, input, voltages[2], want)
+			}
+			for m := BackwardEuler; m <= Gear; m++ {
+				sim.SetMethod(m)
+				sim.Simulate()
+				if len(sim.Unconverged()) != 0 {
+					t.Errorf("%s, %v, %v: unconverged at %v",
+						c.model, input, m, sim.Unconverged())
+				}
+				if out := last(sim.VoltagesOfNode(2)); !near(out, want, 1e-6) {
+					t.Errorf("%s, %v, %v: output %v, want %v",
+						c.model, input, m, out, want)
+				}
+			}
+		}
+	}
+}
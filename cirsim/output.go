@@ -0,0 +1,32 @@
+package cirsim
+
+// Output is a measurement of the circuit,
+// which runs the analysis it needs on its own.
+type Output func(sim Simulator) float64
+
+// DCVoltage measures the voltage of the node at the operating point.
+func DCVoltage(node int) Output {
+	return func(sim Simulator) float64 {
+		voltages, _, _ := sim.OperatingPoint()
+		return voltages[node]
+	}
+}
+
+// ACMagnitude measures the magnitude of the node voltage
+// in the small-signal analysis at the frequency.
+func ACMagnitude(node int, frequency float64) Output {
+	return func(sim Simulator) float64 {
+		_, magnitudes, _, _ := sim.AC(frequency, frequency, 1, false)
+		return magnitudes[node][0]
+	}
+}
+
+// TransientVoltage measures the voltage of the node
+// at the time of the transient simulation.
+func TransientVoltage(node int, time float64) Output {
+	return func(sim Simulator) float64 {
+		sim.Simulate()
+		times := sim.Times()
+		return interpolate(times, sim.VoltagesOfNode(node), []float64{time})[0]
+	}
+}
@@ -0,0 +1,100 @@
+package cirsim
+
+import (
+	"log"
+	"math/cmplx"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// rootPrecision is the relative size of eigenvalues taken for zero:
+// they mean roots at infinity, and of roots taken for the origin,
+// while roots larger than the shift divided by it are taken for infinite.
+const rootPrecision float64 = 1e-9
+
+// PoleZero returns poles and zeros in rad/s of the transfer function
+// from the source to the node voltage of the circuit linearized
+// at its operating point.
+func (sim *simulation) PoleZero(input, node int) (
+	[]complex128, []complex128, bool,
+) {
+	m, ok := sim.components[input].Modeler.(exciter)
+	if !ok {
+		log.Fatal("pole-zero analysis input is not a source")
+	}
+	l, converged := sim.linearize()
+	if node == 0 {
+		// the ground voltage does not depend on the input:
+		return nil, nil, converged
+	}
+	s := newSystem(len(sim.nodeVoltages), sim.branches)
+	m.excite(sim.port(sim.components[input], s, l.step, l.solution), 1)
+	// the ground is dropped with its equation, column and the additional row:
+	_, N := l.conductance.Dims()
+	n := N - 1
+	g := mat.DenseCopyOf(l.conductance.Slice(1, N, 1, N))
+	c := mat.DenseCopyOf(l.capacitance.Slice(1, N, 1, N))
+	poles, solved := roots(g, c)
+	converged = converged && solved
+	// zeros make the system bordered by the input and the output singular:
+	//
+	//	[G+sC -b] [x]   [0]
+	//	[e     0] [u] = [0]
+	a := mat.NewDense(n+1, n+1, nil)
+	a.Slice(0, n, 0, n).(*mat.Dense).Copy(g)
+	for i := 0; i != n; i++ {
+		a.Set(i, n, -s.currents.AtVec(1+i))
+	}
+	a.Set(n, node-1, 1)
+	b := mat.NewDense(n+1, n+1, nil)
+	b.Slice(0, n, 0, n).(*mat.Dense).Copy(c)
+	zeros, solved := roots(a, b)
+	return poles, zeros, converged && solved
+}
+
+// roots returns finite s making a+s*b singular, ordered by magnitude.
+// They come from eigenvalues mu of (a+shift*b)^-1*b as s = shift-1/mu,
+// where the shift keeps the matrix invertible for roots at the origin.
+// It also reports if the roots are found.
+func roots(a, b *mat.Dense) ([]complex128, bool) {
+	if mat.Norm(b, 1) == 0 {
+		return nil, true
+	}
+	shift := mat.Norm(a, 1) / mat.Norm(b, 1)
+	var shifted, m mat.Dense
+	shifted.Scale(shift, b)
+	shifted.Add(a, &shifted)
+	if err := m.Solve(&shifted, b); err != nil {
+		return nil, false
+	}
+	var eigen mat.Eigen
+	if !eigen.Factorize(&m, mat.EigenNone) {
+		return nil, false
+	}
+	values := eigen.Values(nil)
+	largest := 0.0
+	for _, mu := range values {
+		if cmplx.Abs(mu) > largest {
+			largest = cmplx.Abs(mu)
+		}
+	}
+	var res []complex128
+	for _, mu := range values {
+		if cmplx.Abs(mu) <= rootPrecision*largest {
+			continue
+		}
+		root := complex(shift, 0) - 1/mu
+		if cmplx.Abs(root) > shift/rootPrecision {
+			continue
+		}
+		if cmplx.Abs(root) <= rootPrecision*shift {
+			root = 0
+		}
+		res = append(res, root)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return cmplx.Abs(res[i]) < cmplx.Abs(res[j])
+	})
+	return res, true
+}
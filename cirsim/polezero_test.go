@@ -0,0 +1,53 @@
+package cirsim
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestPoleOfRCFilter(t *testing.T) {
+	sim := newTestSimulation(3,
+		settings{"voltage", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"capacitor", []int{2, 0}})
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+	sim.ModelerOfComponent(2).UpdateParameter("Capacitance", 0.000001)
+	poles, zeros, converged := sim.PoleZero(0, 2)
+	if !converged {
+		t.Fatal("pole-zero analysis did not converge")
+	}
+	if len(poles) != 1 || cmplx.Abs(poles[0]+1000) > 0.01 {
+		t.Errorf("poles %v, want [-1000]", poles)
+	}
+	if len(zeros) != 0 {
+		t.Errorf("zeros %v, want none", zeros)
+	}
+}
+
+func TestPolesOfSeriesRLC(t *testing.T) {
+	// 1/(LCs^2+RCs+1) has poles at -R/2L+-j*sqrt(1/LC-(R/2L)^2)
+	// and only zeros at infinity:
+	sim := newTestSimulation(4,
+		settings{"voltage", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"inductor", []int{2, 3}},
+		settings{"capacitor", []int{3, 0}})
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 10)
+	sim.ModelerOfComponent(2).UpdateParameter("Inductance", 0.001)
+	sim.ModelerOfComponent(3).UpdateParameter("Capacitance", 0.000001)
+	poles, zeros, converged := sim.PoleZero(0, 3)
+	if !converged {
+		t.Fatal("pole-zero analysis did not converge")
+	}
+	imaginary := math.Sqrt(1e9 - 5000*5000)
+	if len(poles) != 2 ||
+		cmplx.Abs(poles[0]-complex(-5000, -imaginary)) > 0.01 &&
+			cmplx.Abs(poles[0]-complex(-5000, imaginary)) > 0.01 ||
+		cmplx.Abs(poles[0]-cmplx.Conj(poles[1])) > 0.01 {
+		t.Errorf("poles %v, want -5000+-%vj", poles, imaginary)
+	}
+	if len(zeros) != 0 {
+		t.Errorf("zeros %v, want none", zeros)
+	}
+}
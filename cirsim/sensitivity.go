@@ -0,0 +1,60 @@
+package cirsim
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	sensitivityStep    float64 = 0.001
+	sensitivityMinStep float64 = 0.000001
+)
+
+// Sensitivity is the derivative of the output by the parameter.
+// Normalized one is the change of the output per relative change
+// of the parameter, so sensitivities of different parameters compare.
+type Sensitivity struct {
+	Component  int
+	Parameter  string
+	Value      float64
+	Derivative float64
+	Normalized float64
+}
+
+// Sensitivities finds derivatives of the output by every parameter
+// of every component by central finite differences and returns them
+// starting from the largest normalized one.  Fixed parameters,
+// like times of PWL points or the zero gain of the ideal op-amp,
+// are not varied.
+func (sim *simulation) Sensitivities(output Output) []Sensitivity {
+	var res []Sensitivity
+	for i, c := range sim.components {
+		params := c.Parameters()
+		names := make([]string, 0, len(params))
+		for name := range params {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if m, ok := c.Modeler.(fixer); ok && m.fixed(name) {
+				continue
+			}
+			value := params[name]
+			step := math.Max(math.Abs(value)*sensitivityStep, sensitivityMinStep)
+			c.UpdateParameter(name, value+step)
+			up := output(sim)
+			c.UpdateParameter(name, value-step)
+			down := output(sim)
+			c.UpdateParameter(name, value)
+			derivative := (up - down) / (2 * step)
+			res = append(res,
+				Sensitivity{i, name, value, derivative, derivative * value})
+		}
+	}
+	sort.SliceStable(res, func(a, b int) bool {
+		return math.Abs(res[a].Normalized) > math.Abs(res[b].Normalized)
+	})
+	// leave the simulation in the nominal state:
+	output(sim)
+	return res
+}
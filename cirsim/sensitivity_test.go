@@ -0,0 +1,43 @@
+package cirsim
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSensitivitiesOfDivider(t *testing.T) {
+	// the ideal op-amp buffers the divider,
+	// while the PWL source is there only for its times:
+	sim := newTestSimulation(6,
+		settings{"vdc", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"resistor", []int{2, 0}},
+		settings{"opamp", []int{4, 2, 4}},
+		settings{"vpwl", []int{0, 5}},
+		settings{"resistor", []int{5, 0}})
+	sim.ModelerOfComponent(3).UpdateParameter("Gain", 0)
+	sensitivities := sim.Sensitivities(DCVoltage(4))
+	// the output is R2/(R1+R2), so both normalized ones are 1/4:
+	found := 0
+	for _, s := range sensitivities {
+		if s.Component == 3 && s.Parameter == "Gain" ||
+			s.Component == 4 && strings.HasPrefix(s.Parameter, "Time") {
+			t.Errorf("fixed %s of %d is varied", s.Parameter, s.Component)
+		}
+		if s.Parameter != "Resistance" || s.Component > 2 {
+			continue
+		}
+		found++
+		want := 0.25
+		if s.Component == 1 {
+			want = -0.25
+		}
+		if !near(s.Normalized, want, 1e-6) {
+			t.Errorf("resistor %d: %v, want %v",
+				s.Component, s.Normalized, want)
+		}
+	}
+	if found != 2 {
+		t.Errorf("%d resistors of the divider, want 2", found)
+	}
+}
@@ -1,54 +1,176 @@
 package cirsim
 
-import "gonum.org/v1/gonum/mat"
+import (
+	"log"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
 
 const (
-	defaultPeriod float64 = 0.01
-	iterations    int     = 1000
+	defaultPeriod        float64 = 0.01
+	defaultTolerance     float64 = 0.000001
+	defaultMaxIterations int     = 100
+	defaultMethod        Method  = Trapezoidal
+	relativeTolerance    float64 = 0.001
+	truncationTolerance  float64 = 7
+	minSteps             int     = 100
+	minStepRatio         float64 = 0.000000001
+	restartStepRatio     float64 = 0.001
+	maxSteps             int     = 1000000
+	snapRatio            float64 = 0.01
+	shootingIterations   int     = 20
+	shootingStep         float64 = 0.001
+	maxCycles            int     = 100
+	cycleTolerance       float64 = 0.000001
+	residualTolerance    float64 = 0.000000001
 )
 
 type Simulator interface {
 	Period() float64
 	SetPeriod(float64)
+	Tolerance() float64
+	SetTolerance(float64)
+	MaxIterations() int
+	SetMaxIterations(int)
+	Method() Method
+	SetMethod(Method)
+	InitialOperatingPoint() bool
+	SetInitialOperatingPoint(bool)
+	SteadyState() bool
+	SetSteadyState(bool)
+	Cycle() float64
+	Unconverged() []float64
+	Times() []float64
 	VoltageRange() (float64, float64)
 	CurrentRange() (float64, float64)
 	VoltagesOfNode(i int) []float64
 	CurrentsOfComponent(i int) []float64
 	ModelerOfComponent(i int) Modeler
+	WaveformOfComponent(i int) string
+	SetWaveformOfComponent(i int, name string)
 	Simulate()
+	OperatingPoint() (voltages, currents []float64, converged bool)
+	AC(start, stop float64, points int, logarithmic bool) (
+		frequencies []float64, magnitudes, phases [][]float64, converged bool)
+	DCSweep(component int, parameter string, values []float64) (
+		voltages, currents [][]float64, converged bool)
+	ParametricSweep(component int, parameter string, values []float64) []Waveforms
+	ParameterTolerance(component int, parameter string) (
+		relative float64, distribution Distribution)
+	SetParameterTolerance(component int, parameter string,
+		relative float64, distribution Distribution)
+	MonteCarlo(runs int, seed int64) (times []float64, voltages []Envelope)
+	MonteCarloAC(runs int, seed int64,
+		start, stop float64, points int, logarithmic bool,
+	) (frequencies []float64, magnitudes []Envelope, converged bool)
+	WorstCase(output Output, exhaustive bool) Corners
+	Sensitivities(output Output) []Sensitivity
+	Noise(node int, start, stop float64, points int, logarithmic bool) (
+		frequencies, output, input []float64, converged bool)
+	PoleZero(input, node int) (poles, zeros []complex128, converged bool)
+	TransferFunction(input, node int) (
+		gain, inputResistance, outputResistance float64, converged bool)
 }
 
 type simulation struct {
-	period       float64
-	voltageMax   float64
-	voltageMin   float64
-	currentMax   float64
-	currentMin   float64
-	nodeVoltages [][]float64
-	components   []*component
+	period        float64
+	tolerance     float64
+	maxIterations int
+	method        Method
+	unconverged   []float64
+	times         []float64
+	voltageMax    float64
+	voltageMin    float64
+	currentMax    float64
+	currentMin    float64
+	nodeVoltages  [][]float64
+	components    []*component
+	branches      int
+
+	// start the transient from the operating point instead of zero:
+	initialOperatingPoint bool
+	// or from the periodic steady state, if sources repeat:
+	steadyState bool
 }
 
 func New(nodesCount int, components []ComponentSettings) Simulator {
 	var sim simulation
 	sim.period = defaultPeriod
+	sim.tolerance = defaultTolerance
+	sim.maxIterations = defaultMaxIterations
+	sim.method = defaultMethod
 	sim.nodeVoltages = make([][]float64, nodesCount)
-	for i := range sim.nodeVoltages {
-		sim.nodeVoltages[i] = make([]float64, iterations)
-	}
 	sim.components = make([]*component, 0)
 	for _, c := range components {
-		sim.components = append(sim.components, newComponent(c))
+		comp := newComponent(c)
+		comp.branch = sim.branches
+		sim.branches += comp.branches()
+		sim.components = append(sim.components, comp)
+	}
+	for _, c := range sim.components {
+		if m, ok := c.Modeler.(linker); ok {
+			m.link(sim.linked(c))
+		}
 	}
 	sim.Simulate()
 	return &sim
 }
 
+// linked returns components referenced by terminals of the component.
+func (sim *simulation) linked(c *component) []*component {
+	res := make([]*component, len(c.nodes))
+	for k, i := range c.nodes {
+		if i < 0 || i >= len(sim.components) {
+			log.Fatal("wrong linked component")
+		}
+		res[k] = sim.components[i]
+	}
+	return res
+}
+
 func (sim *simulation) Period() float64 {
 	return sim.period
 }
 func (sim *simulation) SetPeriod(period float64) {
 	sim.period = period
 }
+func (sim *simulation) Tolerance() float64 {
+	return sim.tolerance
+}
+func (sim *simulation) SetTolerance(tolerance float64) {
+	sim.tolerance = tolerance
+}
+func (sim *simulation) MaxIterations() int {
+	return sim.maxIterations
+}
+func (sim *simulation) SetMaxIterations(maxIterations int) {
+	sim.maxIterations = maxIterations
+}
+func (sim *simulation) Method() Method {
+	return sim.method
+}
+func (sim *simulation) SetMethod(method Method) {
+	sim.method = method
+}
+func (sim *simulation) InitialOperatingPoint() bool {
+	return sim.initialOperatingPoint
+}
+func (sim *simulation) SetInitialOperatingPoint(initial bool) {
+	sim.initialOperatingPoint = initial
+}
+func (sim *simulation) SteadyState() bool {
+	return sim.steadyState
+}
+func (sim *simulation) SetSteadyState(steady bool) {
+	sim.steadyState = steady
+}
+func (sim *simulation) Unconverged() []float64 {
+	return sim.unconverged
+}
+func (sim *simulation) Times() []float64 {
+	return sim.times
+}
 func (sim *simulation) VoltageRange() (float64, float64) {
 	return sim.voltageMin, sim.voltageMax
 }
@@ -65,65 +187,474 @@ func (sim *simulation) ModelerOfComponent(i int) Modeler {
 	return sim.components[i]
 }
 
+// WaveformOfComponent returns the name of the waveform of the source
+// or the empty string, if the component is not a source.
+func (sim *simulation) WaveformOfComponent(i int) string {
+	if m, ok := sim.components[i].Modeler.(shaper); ok {
+		return waveformName(m.shape())
+	}
+	return ""
+}
+
+// SetWaveformOfComponent replaces the waveform of the source
+// by the new one with default parameters and no tolerances.
+func (sim *simulation) SetWaveformOfComponent(i int, name string) {
+	m, ok := sim.components[i].Modeler.(shaper)
+	if !ok {
+		log.Fatal("component has no waveform")
+	}
+	m.reshape(newWaveform(name))
+	sim.components[i].tolerances = make(map[string]tolerance)
+}
+
+// Simulate steps through the period with the variable step,
+// which is chosen to keep the local truncation error within tolerance,
+// so it grows on smooth parts up to the period divided by minSteps.
 func (sim *simulation) Simulate() {
 	sim.nullify()
-	N := len(sim.nodeVoltages)
-	delta := sim.period / float64(iterations)
-	for i := 0; i != iterations; i++ {
-		// fill conductances and currents:
-		time := float64(i) * sim.period / float64(iterations)
-		conductances := mat.NewDense(N+1, N, nil)
-		currents := mat.NewVecDense(N+1, nil)
-		for _, c := range sim.components {
-			voltage := sim.nodeVoltages[c.nodes[1]][i] -
-				sim.nodeVoltages[c.nodes[0]][i]
-			current := c.current(time, delta, voltage, c.currentOverTime[i])
-			cond := c.conductance(time, delta, voltage, c.currentOverTime[i])
-			currents.SetVec(c.nodes[1], currents.AtVec(c.nodes[1])-current)
-			currents.SetVec(c.nodes[0], currents.AtVec(c.nodes[0])+current)
-			conductances.Set(c.nodes[1], c.nodes[1],
-				conductances.At(c.nodes[1], c.nodes[1])+cond)
-			conductances.Set(c.nodes[0], c.nodes[0],
-				conductances.At(c.nodes[0], c.nodes[0])+cond)
-			conductances.Set(c.nodes[1], c.nodes[0],
-				conductances.At(c.nodes[1], c.nodes[0])-cond)
-			conductances.Set(c.nodes[0], c.nodes[1],
-				conductances.At(c.nodes[0], c.nodes[1])-cond)
-		}
-		// set up additional row to determine ground node:
-		groundNodeRow := make([]float64, N)
-		groundNodeRow[0] = 1
-		for j := 1; j != N; j++ {
-			groundNodeRow[j] = 0
-		}
-		conductances.SetRow(N, groundNodeRow)
-		currents.SetVec(N, 0)
-		// solve the equation:
-		voltages := mat.NewVecDense(N, nil)
-		voltages.SolveVec(conductances, currents)
-		// save results:
-		for j, n := range sim.nodeVoltages {
-			n[i] = voltages.AtVec(j)
-		}
-		for _, c := range sim.components {
-			voltage := voltages.AtVec(c.nodes[1]) - voltages.AtVec(c.nodes[0])
-			current := c.current(time, delta, voltage, c.currentOverTime[i])
-			cond := c.conductance(time, delta, voltage, c.currentOverTime[i])
-			c.currentOverTime[i] = voltage*cond + current
+	var start *mat.VecDense
+	cycle, common := sim.cycle()
+	if sim.steadyState && !common {
+		// there is no steady state to shoot for:
+		sim.unconverged = append(sim.unconverged, 0)
+	}
+	if sim.steadyState && cycle > 0 {
+		solution, converged := sim.shoot(cycle)
+		// the cycle ends where it starts, so the last shot leaves
+		// components in their state at zero time with the same currents:
+		currents := sim.finalCurrents()
+		sim.forget()
+		if !converged {
+			sim.unconverged = append(sim.unconverged, 0)
+		}
+		sim.record(0, solution, currents)
+		start = solution
+	} else if sim.initialOperatingPoint {
+		t := sim.dcStep()
+		solution, converged := sim.solve(t)
+		if !converged {
+			sim.unconverged = append(sim.unconverged, 0)
 		}
+		sim.save(t, solution)
+		sim.accept(t, solution)
+		start = solution
 	}
+	sim.transient(sim.period, start)
 	sim.updateRanges()
 }
 
-func (sim *simulation) nullify() {
-	for _, n := range sim.nodeVoltages {
-		for i := range n {
-			n[i] = 0
+// transient simulates the period starting from the solution at zero time,
+// if it is given, and returns the final solution.
+func (sim *simulation) transient(
+	period float64, start *mat.VecDense,
+) *mat.VecDense {
+	maxDelta := sim.maxDelta(period)
+	minDelta := maxDelta * minStepRatio
+	// sources are switched on at zero time, so the first step
+	// is a restart from zero state one short step before it:
+	delta := maxDelta * restartStepRatio
+	lastTime := -delta
+	last := mat.NewVecDense(len(sim.nodeVoltages)+sim.branches, nil)
+	previous := last
+	lastDelta := 0.0
+	restart, settling := true, false
+	if start != nil {
+		lastTime, last, previous = 0, start, start
+	}
+	for steps := 0; steps != maxSteps; steps++ {
+		if math.IsNaN(lastTime+delta) || math.IsInf(lastTime+delta, 0) {
+			break
+		}
+		b := sim.breakpoint(lastTime + minDelta)
+		time := stepEnd(lastTime, delta, math.Min(b, period))
+		t := timeStep{
+			time:      time,
+			delta:     time - lastTime,
+			last:      last,
+			previous:  previous,
+			lastDelta: lastDelta,
+			restart:   restart,
+			method:    sim.method,
+		}
+		solution, converged := sim.solve(t)
+		ratio := sim.truncation(t, solution)
+		if t.delta > minDelta && !converged {
+			delta = t.delta / 8
+			continue
+		}
+		if t.delta > minDelta && ratio > 1 {
+			delta = t.delta * sim.stepFactor(ratio)
+			continue
+		}
+		if !converged || !finite(solution) {
+			// even the shortest step cannot get through,
+			// so the transient stops with what it has got:
+			sim.unconverged = append(sim.unconverged, time)
+			if finite(solution) {
+				sim.save(t, solution)
+				sim.accept(t, solution)
+			}
+			return last
+		}
+		sim.save(t, solution)
+		switched := sim.accept(t, solution)
+		if time >= period {
+			return solution
+		}
+		delta = math.Min(t.delta*sim.stepFactor(ratio), maxDelta)
+		delta = math.Max(delta, minDelta)
+		lastTime, last, previous = time, solution, last
+		// derivatives at discontinuities are not the ones of the waveform,
+		// so the history is dropped for two steps after them:
+		lastDelta = t.delta
+		if restart || settling {
+			lastDelta = 0
+		}
+		settling = restart
+		restart = switched || time == b
+		if restart {
+			delta = math.Min(delta, maxDelta*restartStepRatio)
+		}
+	}
+	sim.unconverged = append(sim.unconverged, lastTime)
+	return last
+}
+
+// maxDelta returns the longest step: the period needs minSteps points
+// to be drawn, and so does every cycle of periodic sources.
+func (sim *simulation) maxDelta(period float64) float64 {
+	res := period / float64(minSteps)
+	for _, c := range sim.components {
+		if m, ok := c.Modeler.(periodic); ok && m.cycle() > 0 {
+			res = math.Min(res, m.cycle()/float64(minSteps))
+		}
+	}
+	return res
+}
+
+// stepEnd returns the end of the step, which is stretched onto the target,
+// if it would leave too short a gap before it: a tiny step makes
+// derivatives out of rounding errors.
+func stepEnd(lastTime, delta, target float64) float64 {
+	if target-(lastTime+delta) < delta*snapRatio {
+		return target
+	}
+	return lastTime + delta
+}
+
+func finite(v *mat.VecDense) bool {
+	for j := 0; j != v.Len(); j++ {
+		if math.IsNaN(v.AtVec(j)) || math.IsInf(v.AtVec(j), 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// Cycle returns the common cycle of the periodic sources
+// or zero, if there is none.
+func (sim *simulation) Cycle() float64 {
+	cycle, _ := sim.cycle()
+	return cycle
+}
+
+// cycle returns the common cycle of the periodic sources,
+// the shortest one made up of whole cycles of each of them.
+// It also reports if there is such a cycle within maxCycles
+// of the longest one, which is zero without periodic sources.
+func (sim *simulation) cycle() (float64, bool) {
+	var cycles []float64
+	longest := 0.0
+	for _, c := range sim.components {
+		if m, ok := c.Modeler.(periodic); ok && m.cycle() > 0 {
+			cycles = append(cycles, m.cycle())
+			longest = math.Max(longest, m.cycle())
+		}
+	}
+	for k := 1; k <= maxCycles; k++ {
+		common := longest * float64(k)
+		whole := true
+		for _, c := range cycles {
+			n := math.Round(common / c)
+			whole = whole && math.Abs(common-n*c) <= cycleTolerance*common
+		}
+		if whole {
+			return common, true
+		}
+	}
+	return 0, false
+}
+
+// shoot finds the solution at zero time, which the circuit returns to
+// after the cycle, by Newton iterations starting from the operating point.
+// The Jacobian of the cycle is found by finite differences,
+// shooting once more with every unknown shifted a bit,
+// and then only corrected by the Broyden's update after every shot.
+func (sim *simulation) shoot(cycle float64) (*mat.VecDense, bool) {
+	_, start, _ := sim.operatingPoint()
+	N := start.Len()
+	var jacobian *mat.Dense
+	var lastStart, lastResidual *mat.VecDense
+	for k := 0; k != shootingIterations; k++ {
+		sim.nullify()
+		end := sim.transient(cycle, start)
+		if len(sim.unconverged) != 0 || !finite(end) {
+			return start, false
+		}
+		if sim.converged(start, end) {
+			return start, true
+		}
+		residual := mat.NewVecDense(N, nil)
+		residual.SubVec(end, start)
+		if jacobian == nil {
+			jacobian = sim.cycleJacobian(cycle, start, end)
+		} else {
+			broyden(jacobian, lastStart, start, lastResidual, residual)
+		}
+		// the correction zeroes the linearized difference of end and start:
+		correction := mat.NewVecDense(N, nil)
+		if correction.SolveVec(jacobian, residual) != nil {
+			return start, false
 		}
+		lastStart, lastResidual = mat.VecDenseCopyOf(start), residual
+		start.SubVec(start, correction)
 	}
+	return start, false
+}
+
+// cycleJacobian returns the derivatives of the difference of the end
+// of the cycle and its start over the start by finite differences.
+func (sim *simulation) cycleJacobian(
+	cycle float64, start, end *mat.VecDense,
+) *mat.Dense {
+	N := start.Len()
+	jacobian := mat.NewDense(N, N, nil)
+	for j := 0; j != N; j++ {
+		step := shootingStep * math.Max(1, math.Abs(start.AtVec(j)))
+		shifted := mat.VecDenseCopyOf(start)
+		shifted.SetVec(j, start.AtVec(j)+step)
+		sim.nullify()
+		shiftedEnd := sim.transient(cycle, shifted)
+		for i := 0; i != N; i++ {
+			jacobian.Set(i, j, (shiftedEnd.AtVec(i)-end.AtVec(i))/step)
+		}
+		jacobian.Set(j, j, jacobian.At(j, j)-1)
+	}
+	return jacobian
+}
+
+// broyden corrects the Jacobian to match the change of the residual
+// between two points, changing it the least.
+func broyden(jacobian *mat.Dense, from, to, before, after *mat.VecDense) {
+	step := mat.NewVecDense(from.Len(), nil)
+	step.SubVec(to, from)
+	length := mat.Dot(step, step)
+	if length == 0 {
+		return
+	}
+	miss := mat.NewVecDense(from.Len(), nil)
+	miss.MulVec(jacobian, step)
+	miss.SubVec(after, miss)
+	miss.SubVec(miss, before)
+	update := mat.NewDense(from.Len(), from.Len(), nil)
+	update.Outer(1/length, miss, step)
+	jacobian.Add(jacobian, update)
+}
+
+// finalCurrents returns currents of components at the last saved point.
+func (sim *simulation) finalCurrents() []float64 {
+	currents := make([]float64, len(sim.components))
+	if len(sim.times) == 0 {
+		return currents
+	}
+	for i, c := range sim.components {
+		currents[i] = c.currentOverTime[len(c.currentOverTime)-1]
+	}
+	return currents
+}
+
+// OperatingPoint solves the circuit at zero time with all time derivatives
+// being zero, so capacitors are open and inductors are shorted.
+// It returns voltages of nodes and currents of components.
+func (sim *simulation) OperatingPoint() ([]float64, []float64, bool) {
+	t, solution, converged := sim.operatingPoint()
+	voltages := make([]float64, len(sim.nodeVoltages))
+	for j := range voltages {
+		voltages[j] = solution.AtVec(j)
+	}
+	currents := make([]float64, len(sim.components))
+	for i, c := range sim.components {
+		currents[i] = c.current(sim.port(c, nil, t, solution))
+	}
+	return voltages, currents, converged
+}
+
+func (sim *simulation) operatingPoint() (timeStep, *mat.VecDense, bool) {
+	sim.reset()
+	t := sim.dcStep()
+	solution, converged := sim.solve(t)
+	return t, solution, converged
+}
+
+func (sim *simulation) dcStep() timeStep {
+	zero := mat.NewVecDense(len(sim.nodeVoltages)+sim.branches, nil)
+	return timeStep{last: zero, previous: zero, dc: true}
+}
+
+// stepFactor returns how much the step has to be changed to get
+// its truncation error under the allowed one with some margin.
+func (sim *simulation) stepFactor(ratio float64) float64 {
+	factor := 0.9 * math.Pow(ratio, -1/(sim.method.order()+1))
+	return math.Max(0.125, math.Min(2, factor))
+}
+
+// solve finds the solution at the time point of the step,
+// iterating from the previous solution until it settles.
+func (sim *simulation) solve(t timeStep) (*mat.VecDense, bool) {
+	solution := t.last
+	converged := false
+	for k := 0; k != sim.maxIterations && !converged; k++ {
+		next, settled := sim.iterate(t, solution)
+		converged = settled && sim.converged(solution, next)
+		solution = next
+	}
+	return solution, converged
+}
+
+// truncation returns the largest truncation error of the components
+// relative to the allowed one.
+func (sim *simulation) truncation(
+	t timeStep, solution *mat.VecDense,
+) float64 {
+	res := 0.0
+	for _, c := range sim.components {
+		if m, ok := c.Modeler.(truncator); ok {
+			res = math.Max(res, m.truncation(sim.port(c, nil, t, solution)))
+		}
+	}
+	return res
+}
+
+// save appends the solution to the results.
+func (sim *simulation) save(t timeStep, solution *mat.VecDense) {
+	currents := make([]float64, len(sim.components))
+	for i, c := range sim.components {
+		currents[i] = c.current(sim.port(c, nil, t, solution))
+	}
+	sim.record(t.time, solution, currents)
+}
+
+func (sim *simulation) record(
+	time float64, solution *mat.VecDense, currents []float64,
+) {
+	sim.times = append(sim.times, time)
+	for j := range sim.nodeVoltages {
+		sim.nodeVoltages[j] = append(sim.nodeVoltages[j], solution.AtVec(j))
+	}
+	for i, c := range sim.components {
+		c.currentOverTime = append(c.currentOverTime, currents[i])
+	}
+}
+
+// iterate does one Newton-Raphson iteration,
+// linearizing every component around the given guess.
+// The iteration cannot be considered converged, if some component
+// had to limit its voltages or the system is singular,
+// which is reported as not settled.
+func (sim *simulation) iterate(
+	t timeStep, guess *mat.VecDense,
+) (*mat.VecDense, bool) {
+	s := newSystem(len(sim.nodeVoltages), sim.branches)
+	if t.dc {
+		// nodes connected only through capacitors are open at DC:
+		s.shunt(gmin)
+	}
+	limited := false
+	for _, c := range sim.components {
+		p := sim.port(c, s, t, guess)
+		c.stamp(p)
+		limited = limited || p.limited
+	}
+	solution, solved := s.solve()
+	return solution, solved && !limited
+}
+
+// accept lets stateful components remember the solution of the step
+// and reports if some of them switched.
+func (sim *simulation) accept(t timeStep, solution *mat.VecDense) bool {
+	switched := false
+	for _, c := range sim.components {
+		if m, ok := c.Modeler.(stateful); ok {
+			m.accept(sim.port(c, nil, t, solution))
+		}
+		if m, ok := c.Modeler.(switcher); ok {
+			switched = switched || m.switched()
+		}
+	}
+	return switched
+}
+
+// breakpoint returns the earliest breakpoint after the given time.
+func (sim *simulation) breakpoint(after float64) float64 {
+	res := math.Inf(1)
+	for _, c := range sim.components {
+		if m, ok := c.Modeler.(switcher); ok {
+			res = math.Min(res, m.breakpoint(after))
+		}
+	}
+	return res
+}
+
+func (sim *simulation) port(
+	c *component, s *system, t timeStep, guess *mat.VecDense,
+) *port {
+	return &port{
+		timeStep:  t,
+		s:         s,
+		nodes:     c.nodes,
+		branch:    len(sim.nodeVoltages) + c.branch,
+		guess:     guess,
+		tolerance: sim.tolerance,
+	}
+}
+
+func (sim *simulation) converged(prev, next *mat.VecDense) bool {
+	for j := 0; j != next.Len(); j++ {
+		a := prev.AtVec(j)
+		b := next.AtVec(j)
+		if math.IsNaN(b) || math.IsInf(b, 0) {
+			return false
+		}
+		if math.Abs(a-b) > sim.tolerance+
+			relativeTolerance*math.Max(math.Abs(a), math.Abs(b)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (sim *simulation) nullify() {
+	sim.reset()
+	sim.forget()
+}
+
+// forget drops the results keeping the state of components.
+func (sim *simulation) forget() {
+	sim.unconverged = nil
+	sim.times = nil
+	for i := range sim.nodeVoltages {
+		sim.nodeVoltages[i] = nil
+	}
+	for _, c := range sim.components {
+		c.currentOverTime = nil
+	}
+}
+
+// reset brings stateful models to their initial state.
+func (sim *simulation) reset() {
 	for _, c := range sim.components {
-		for i := range c.currentOverTime {
-			c.currentOverTime[i] = 0
+		if m, ok := c.Modeler.(stateful); ok {
+			m.reset()
 		}
 	}
 }
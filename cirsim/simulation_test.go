@@ -0,0 +1,400 @@
+package cirsim
+
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+type settings struct {
+	model string
+	nodes []int
+}
+
+func (s settings) Nodes() []int      { return s.nodes }
+func (s settings) ModelName() string { return s.model }
+
+func newTestSimulation(nodes int, components ...settings) *simulation {
+	cs := make([]ComponentSettings, len(components))
+	for i, c := range components {
+		cs[i] = c
+	}
+	return New(nodes, cs).(*simulation)
+}
+
+func last(values []float64) float64 {
+	return values[len(values)-1]
+}
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+func TestVoltageSourceAcrossCapacitor(t *testing.T) {
+	for _, source := range []string{"vdc", "voltage"} {
+		sim := newTestSimulation(2,
+			settings{source, []int{0, 1}},
+			settings{"capacitor", []int{0, 1}})
+		for m := BackwardEuler; m <= Gear; m++ {
+			sim.SetMethod(m)
+			sim.Simulate()
+			if len(sim.Unconverged()) != 0 {
+				t.Errorf("%s, %v: unconverged at %v",
+					source, m, sim.Unconverged())
+			}
+			if last(sim.Times()) != sim.Period() {
+				t.Errorf("%s, %v: ended at %v", source, m, last(sim.Times()))
+			}
+		}
+	}
+}
+
+func TestCurrentSourceIntoInductor(t *testing.T) {
+	sim := newTestSimulation(2,
+		settings{"idc", []int{0, 1}},
+		settings{"inductor", []int{0, 1}})
+	for m := BackwardEuler; m <= Gear; m++ {
+		sim.SetMethod(m)
+		sim.Simulate()
+		if len(sim.Unconverged()) != 0 {
+			t.Errorf("%v: unconverged at %v", m, sim.Unconverged())
+		}
+		// the current is constant, so there is no voltage after the start:
+		if v := last(sim.VoltagesOfNode(1)); !near(v, 0, 1e-9) {
+			t.Errorf("%v: voltage %v, want 0", m, v)
+		}
+		if i := last(sim.CurrentsOfComponent(1)); !near(i, -1, 1e-9) {
+			t.Errorf("%v: current %v, want -1", m, i)
+		}
+	}
+}
+
+func TestNaNIsNotConverged(t *testing.T) {
+	sim := newTestSimulation(2, settings{"resistor", []int{0, 1}})
+	prev := mat.NewVecDense(2, nil)
+	next := mat.NewVecDense(2, []float64{0, math.NaN()})
+	if sim.converged(prev, next) {
+		t.Error("NaN solution is taken as converged")
+	}
+	next.SetVec(1, math.Inf(1))
+	if sim.converged(prev, next) {
+		t.Error("infinite solution is taken as converged")
+	}
+}
+
+func TestStepEndSnapsOntoTarget(t *testing.T) {
+	// rounding leaves the accumulated time just short of the period:
+	if end := stepEnd(0.00999999999999976, 0.00001, 0.01); end != 0.01 {
+		t.Errorf("step ends at %v, want 0.01", end)
+	}
+	if end := stepEnd(0.001, 0.00001, 0.002); end != 0.00101 {
+		t.Errorf("step ends at %v, want 0.00101", end)
+	}
+	if end := stepEnd(0.001, 0.00001, 0.001005); end != 0.001005 {
+		t.Errorf("step ends at %v, want 0.001005", end)
+	}
+}
+
+func TestInvertingAmplifierSettles(t *testing.T) {
+	sim := newTestSimulation(4,
+		settings{"vdc", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"resistor", []int{2, 3}},
+		settings{"opamp", []int{2, 0, 3}})
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+	sim.ModelerOfComponent(2).UpdateParameter("Resistance", 10000)
+	// the gain of 100000 leaves the error of 11/100000:
+	want := -10 / (1 + 11.0/100000)
+	for m := BackwardEuler; m <= Gear; m++ {
+		sim.SetMethod(m)
+		for _, initial := range []bool{false, true} {
+			sim.SetInitialOperatingPoint(initial)
+			sim.Simulate()
+			times := sim.Times()
+			n := len(times)
+			if times[n-1]-times[n-2] < snapRatio*(times[n-2]-times[n-3]) {
+				t.Errorf("%v: the last step is %v", m, times[n-1]-times[n-2])
+			}
+			if out := last(sim.VoltagesOfNode(3)); !near(out, want, 0.0001) {
+				t.Errorf("%v: output %v, want %v", m, out, want)
+			}
+			if v := last(sim.VoltagesOfNode(2)); !near(v, -want/100000, 1e-6) {
+				t.Errorf("%v: virtual ground at %v", m, v)
+			}
+		}
+	}
+}
+
+func TestSlowRCKeepsCurrentLaw(t *testing.T) {
+	sim := newTestSimulation(3,
+		settings{"vdc", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"capacitor", []int{2, 0}})
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 10000)
+	sim.ModelerOfComponent(2).UpdateParameter("Capacitance", 0.001)
+	for _, period := range []float64{0.003, 0.01, 0.0123, 1} {
+		sim.SetPeriod(period)
+		sim.Simulate()
+		// the resistor current flows into the capacitor:
+		ir := last(sim.CurrentsOfComponent(1))
+		ic := last(sim.CurrentsOfComponent(2))
+		if !near(ir, ic, 1e-9) {
+			t.Errorf("period %v: resistor %v, capacitor %v", period, ir, ic)
+		}
+		want := -math.Exp(-period/10) / 10000
+		if !near(ic, want, 0.001*math.Abs(want)) {
+			t.Errorf("period %v: current %v, want %v", period, ic, want)
+		}
+	}
+}
+
+func TestStepFollowsTruncationError(t *testing.T) {
+	// the pulse charges the capacitor from 1ms to 3ms with tau of 1ms:
+	sim := newTestSimulation(3,
+		settings{"vpulse", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"capacitor", []int{2, 0}})
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+	for m := BackwardEuler; m <= Gear; m++ {
+		sim.SetMethod(m)
+		sim.Simulate()
+		times := sim.Times()
+		voltages := sim.VoltagesOfNode(2)
+		// the first order method accumulates more error:
+		tolerance := 0.002
+		if m == BackwardEuler {
+			tolerance = 0.01
+		}
+		shortest, longest := math.Inf(1), 0.0
+		for i := 1; i != len(times); i++ {
+			delta := times[i] - times[i-1]
+			if times[i] > 0.0009 && times[i] < 0.0011 {
+				shortest = math.Min(shortest, delta)
+			}
+			longest = math.Max(longest, delta)
+			if times[i] > 0.0015 && times[i] < 0.003 {
+				want := 1 - math.Exp(-(times[i]-0.0010005)/0.001)
+				if !near(voltages[i], want, tolerance) {
+					t.Errorf("%v: %v at %vs, want %v",
+						m, voltages[i], times[i], want)
+				}
+			}
+		}
+		if shortest > 0.000001 {
+			t.Errorf("%v: shortest step at the edge is %v", m, shortest)
+		}
+		if longest <= sim.Period()/1000 {
+			t.Errorf("%v: the step never grows beyond %v", m, longest)
+		}
+	}
+}
+
+func TestTruncationRejectsKinks(t *testing.T) {
+	p := port{timeStep: timeStep{delta: 0.001, lastDelta: 0.001},
+		tolerance: defaultTolerance}
+	for m := BackwardEuler; m <= Gear; m++ {
+		p.method = m
+		// derivatives of a quadratic change linearly, which is exact
+		// for second order methods:
+		r := p.truncation(0.003, 0.002, 0.001)
+		if m != BackwardEuler && r > 0.000001 {
+			t.Errorf("%v: smooth step is rejected with %v", m, r)
+		}
+		if r := p.truncation(1, 0, 0); r <= 1 {
+			t.Errorf("%v: the kink is accepted with %v", m, r)
+		}
+	}
+	p.restart = true
+	if r := p.truncation(1, 0, 0); r != 0 {
+		t.Errorf("the error right after a restart is %v", r)
+	}
+}
+
+func TestOperatingPoint(t *testing.T) {
+	// the capacitor is open and the inductor is shorted at DC:
+	sim := newTestSimulation(4,
+		settings{"vdc", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"inductor", []int{2, 3}},
+		settings{"resistor", []int{3, 0}},
+		settings{"capacitor", []int{2, 0}})
+	sim.ModelerOfComponent(0).UpdateParameter("DC", 10)
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+	sim.ModelerOfComponent(3).UpdateParameter("Resistance", 1000)
+	voltages, currents, converged := sim.OperatingPoint()
+	if !converged {
+		t.Fatal("operating point did not converge")
+	}
+	for i, want := range []float64{0, 10, 5, 5} {
+		if !near(voltages[i], want, 1e-6) {
+			t.Errorf("node %d at %v, want %v", i, voltages[i], want)
+		}
+	}
+	if !near(math.Abs(currents[2]), 0.005, 1e-9) {
+		t.Errorf("inductor current %v, want 5mA", currents[2])
+	}
+	if !near(currents[4], 0, 1e-9) {
+		t.Errorf("capacitor current %v, want 0", currents[4])
+	}
+	// starting from the operating point, nothing changes:
+	sim.SetInitialOperatingPoint(true)
+	sim.Simulate()
+	for _, v := range sim.VoltagesOfNode(2) {
+		if !near(v, 5, 1e-6) {
+			t.Fatalf("node 2 moves to %v", v)
+		}
+	}
+}
+
+func TestOperatingPointOfCapacitorOnlyNode(t *testing.T) {
+	// node 2 is connected only through capacitors:
+	sim := newTestSimulation(3,
+		settings{"vdc", []int{0, 1}},
+		settings{"capacitor", []int{1, 2}},
+		settings{"capacitor", []int{2, 0}})
+	voltages, _, converged := sim.OperatingPoint()
+	if !converged {
+		t.Fatal("operating point did not converge")
+	}
+	if !near(voltages[1], 1, 1e-9) || !near(voltages[2], 0, 1e-9) {
+		t.Errorf("voltages %v, want [0 1 0]", voltages)
+	}
+}
+
+func TestSingularOperatingPointIsNotConverged(t *testing.T) {
+	// parallel sources of different voltages have no solution:
+	sim := newTestSimulation(2,
+		settings{"vdc", []int{0, 1}},
+		settings{"vdc", []int{0, 1}})
+	sim.ModelerOfComponent(1).UpdateParameter("DC", 2)
+	if _, _, converged := sim.OperatingPoint(); converged {
+		t.Error("singular system is taken as converged")
+	}
+	sim.Simulate()
+	if len(sim.Unconverged()) == 0 {
+		t.Error("singular transient is taken as converged")
+	}
+}
+
+func TestSteadyStateOfRCFilter(t *testing.T) {
+	sim := newTestSimulation(3,
+		settings{"voltage", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"capacitor", []int{2, 0}})
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+	sim.ModelerOfComponent(2).UpdateParameter("Capacitance", 0.000001)
+	sim.SetSteadyState(true)
+	// the sine of 1kHz is filtered with omega*R*C of 2*pi:
+	wrc := 2 * math.Pi
+	amplitude := 1 / math.Sqrt(1+wrc*wrc)
+	for m := BackwardEuler; m <= Gear; m++ {
+		sim.SetMethod(m)
+		sim.Simulate()
+		if len(sim.Unconverged()) != 0 {
+			t.Fatalf("%v: unconverged at %v", m, sim.Unconverged())
+		}
+		times := sim.Times()
+		if times[0] != 0 {
+			t.Errorf("%v: starts at %v", m, times[0])
+		}
+		tolerance := 0.002
+		if m == BackwardEuler {
+			tolerance = 0.02
+		}
+		for i, v := range sim.VoltagesOfNode(2) {
+			phase := 2*math.Pi*1000*times[i] - math.Atan(wrc)
+			want := amplitude * math.Sin(phase)
+			if !near(v, want, tolerance*amplitude) {
+				t.Errorf("%v: %v at %vs, want %v", m, v, times[i], want)
+				break
+			}
+		}
+	}
+}
+
+func TestCommonCycle(t *testing.T) {
+	sim := newTestSimulation(2,
+		settings{"voltage", []int{0, 1}},
+		settings{"voltage", []int{0, 1}})
+	sim.ModelerOfComponent(1).UpdateParameter("Frequency", 1500)
+	if cycle, common := sim.cycle(); !common || !near(cycle, 0.002, 1e-12) {
+		t.Errorf("cycle %v, want 0.002", cycle)
+	}
+	sim.ModelerOfComponent(1).UpdateParameter("Frequency", 1000*math.Sqrt2)
+	if cycle, common := sim.cycle(); common {
+		t.Errorf("incommensurate sources have the cycle %v", cycle)
+	}
+}
+
+func TestSourceWaveformIsReplaced(t *testing.T) {
+	sim := newTestSimulation(2,
+		settings{"power", []int{0, 1}},
+		settings{"resistor", []int{1, 0}})
+	power := sim.ModelerOfComponent(0)
+	// the current source keeps the name of its amplitude:
+	power.UpdateParameter("Current", 2)
+	if a := power.Parameters()["Current"]; a != 2 {
+		t.Errorf("current %v, want 2", a)
+	}
+	if w := sim.WaveformOfComponent(0); w != "sin" {
+		t.Errorf("waveform %q, want sin", w)
+	}
+	if w := sim.WaveformOfComponent(1); w != "" {
+		t.Errorf("resistor has the waveform %q", w)
+	}
+	sim.SetWaveformOfComponent(0, "dc")
+	if _, ok := power.Parameters()["DC"]; !ok {
+		t.Errorf("parameters %v of the dc waveform", power.Parameters())
+	}
+	sim.Simulate()
+	// the default resistance is 100 ohms:
+	if v := last(sim.VoltagesOfNode(1)); !near(math.Abs(v), 100, 1e-6) {
+		t.Errorf("voltage %v, want 100", v)
+	}
+}
+
+func TestNewtonRaphsonSolvesDiode(t *testing.T) {
+	// 5V through 1k into the diode, solved by hand by fixed-point
+	// iteration of V = nVt*ln(I/Is+1) with I = (5-V)/1k:
+	sim := newTestSimulation(3,
+		settings{"vdc", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"diode", []int{0, 2}})
+	sim.ModelerOfComponent(0).UpdateParameter("DC", 5)
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+	sim.Simulate()
+	if len(sim.Unconverged()) != 0 {
+		t.Fatalf("unconverged at %v", sim.Unconverged())
+	}
+	if v := last(sim.VoltagesOfNode(2)); !near(v, 0.692544, 1e-5) {
+		t.Errorf("diode voltage %v, want 0.692544", v)
+	}
+	resistor := last(sim.CurrentsOfComponent(1))
+	diode := last(sim.CurrentsOfComponent(2))
+	if !near(math.Abs(resistor), 0.00430746, 1e-8) ||
+		!near(math.Abs(diode), math.Abs(resistor), 1e-10) {
+		t.Errorf("currents %v and %v, want 4.30746mA", resistor, diode)
+	}
+}
+
+func TestVoltageSourceDrivesDivider(t *testing.T) {
+	sim := newTestSimulation(3,
+		settings{"vdc", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"resistor", []int{2, 0}})
+	sim.ModelerOfComponent(0).UpdateParameter("DC", 10)
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+	sim.ModelerOfComponent(2).UpdateParameter("Resistance", 4000)
+	voltages, currents, converged := sim.OperatingPoint()
+	if !converged {
+		t.Fatal("operating point did not converge")
+	}
+	if !near(voltages[1], 10, 1e-6) || !near(voltages[2], 8, 1e-6) {
+		t.Errorf("voltages %v, want 10 and 8", voltages)
+	}
+	// the branch current of the source is the current of the divider:
+	if !near(math.Abs(currents[0]), 0.002, 1e-9) {
+		t.Errorf("source current %v, want 2mA", currents[0])
+	}
+}
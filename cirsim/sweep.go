@@ -0,0 +1,115 @@
+package cirsim
+
+import (
+	"log"
+	"math"
+)
+
+// DCSweep steps the parameter of the component over the values
+// and finds the operating point at every step, starting from
+// the previous one.  It returns voltages of nodes and currents
+// of components at every value.
+// The parameter is restored afterwards.
+func (sim *simulation) DCSweep(
+	component int, parameter string, values []float64,
+) ([][]float64, [][]float64, bool) {
+	m := sim.components[component].Modeler
+	original, ok := m.Parameters()[parameter]
+	if !ok {
+		log.Fatal("wrong parameter name: ", parameter)
+	}
+	defer m.UpdateParameter(parameter, original)
+	points := len(values)
+	voltages := make([][]float64, len(sim.nodeVoltages))
+	for i := range voltages {
+		voltages[i] = make([]float64, points)
+	}
+	currents := make([][]float64, len(sim.components))
+	for i := range currents {
+		currents[i] = make([]float64, points)
+	}
+	sim.reset()
+	t := sim.dcStep()
+	converged := true
+	for k, v := range values {
+		m.UpdateParameter(parameter, v)
+		solution, ok := sim.solve(t)
+		converged = converged && ok
+		sim.accept(t, solution)
+		for i := range voltages {
+			voltages[i][k] = solution.AtVec(i)
+		}
+		for i, c := range sim.components {
+			currents[i][k] = c.current(sim.port(c, nil, t, solution))
+		}
+		t.last = solution
+	}
+	return voltages, currents, converged
+}
+
+// Waveforms are results of a single transient simulation.
+type Waveforms struct {
+	Times       []float64
+	Voltages    [][]float64
+	Currents    [][]float64
+	Unconverged []float64
+	// the common cycle of periodic sources, zero if there is none:
+	Cycle float64
+}
+
+// ParametricSweep runs the transient simulation for every value
+// of the parameter of the component.  The parameter is restored
+// and the nominal simulation is run again afterwards.
+func (sim *simulation) ParametricSweep(
+	component int, parameter string, values []float64,
+) []Waveforms {
+	m := sim.components[component].Modeler
+	original, ok := m.Parameters()[parameter]
+	if !ok {
+		log.Fatal("wrong parameter name: ", parameter)
+	}
+	res := make([]Waveforms, len(values))
+	for i, v := range values {
+		m.UpdateParameter(parameter, v)
+		sim.Simulate()
+		res[i] = sim.waveforms()
+	}
+	m.UpdateParameter(parameter, original)
+	sim.Simulate()
+	return res
+}
+
+// waveforms returns results of the last transient simulation,
+// which are not reused by the next one.
+func (sim *simulation) waveforms() Waveforms {
+	w := Waveforms{
+		Times:       sim.times,
+		Voltages:    make([][]float64, len(sim.nodeVoltages)),
+		Currents:    make([][]float64, len(sim.components)),
+		Unconverged: sim.unconverged,
+		Cycle:       sim.Cycle(),
+	}
+	copy(w.Voltages, sim.nodeVoltages)
+	for i, c := range sim.components {
+		w.Currents[i] = c.currentOverTime
+	}
+	return w
+}
+
+// sweep returns the given number of points from start to stop,
+// which are spaced either evenly or evenly on the logarithmic scale.
+func sweep(start, stop float64, points int, logarithmic bool) []float64 {
+	res := make([]float64, points)
+	for i := range res {
+		fraction := 0.0
+		if points > 1 {
+			fraction = float64(i) / float64(points-1)
+		}
+		if logarithmic {
+			res[i] = start * math.Pow(stop/start, fraction)
+		} else {
+			res[i] = start + (stop-start)*fraction
+		}
+	}
+	return res
+}
@@ -0,0 +1,32 @@
+package cirsim
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDCSweepOfDiode(t *testing.T) {
+	// the source sets the diode voltage, so its current is
+	// Is*(exp(V/Vt)-1) with Is = 1e-14 and Vt = 0.025852:
+	sim := newTestSimulation(2,
+		settings{"vdc", []int{0, 1}},
+		settings{"diode", []int{0, 1}})
+	sim.ModelerOfComponent(0).UpdateParameter("DC", 1.5)
+	values := []float64{0.5, 0.6, 0.7}
+	voltages, currents, converged := sim.DCSweep(0, "DC", values)
+	if !converged {
+		t.Fatal("DC sweep did not converge")
+	}
+	for k, v := range values {
+		if !near(voltages[1][k], v, 1e-9) {
+			t.Errorf("at %v: voltage %v", v, voltages[1][k])
+		}
+		want := 1e-14 * (math.Exp(v/0.025852) - 1)
+		if !near(currents[1][k]/want, 1, 1e-6) {
+			t.Errorf("at %v: current %v, want %v", v, currents[1][k], want)
+		}
+	}
+	if dc := sim.ModelerOfComponent(0).Parameters()["DC"]; dc != 1.5 {
+		t.Errorf("DC is left at %v, want 1.5", dc)
+	}
+}
@@ -0,0 +1,175 @@
+package cirsim
+
+import "math"
+
+// timeSwitch is closed from the close time until the open time.
+// The state is changed by the accepted step ending at the breakpoint,
+// so the step before it is still solved in the old state.
+type timeSwitch struct {
+	onResistance  float64
+	offResistance float64
+	closeTime     float64
+	openTime      float64
+	closed        bool
+	changed       bool
+}
+
+func newTimeSwitch() *timeSwitch {
+	return &timeSwitch{
+		onResistance:  0.001,
+		offResistance: 1000000000.0,
+		closeTime:     0.002,
+		openTime:      0.006,
+	}
+}
+
+func (m *timeSwitch) conductance(time, voltage float64) float64 {
+	if m.closed {
+		return 1 / m.onResistance
+	}
+	return 1 / m.offResistance
+}
+func (m *timeSwitch) current(time, voltage float64) float64 {
+	return 0
+}
+func (m *timeSwitch) closes(time float64) bool {
+	return m.closeTime <= time && time < m.openTime
+}
+func (m *timeSwitch) reset() {
+	m.closed = m.closes(0)
+	m.changed = false
+}
+func (m *timeSwitch) accept(p *port) {
+	closed := m.closes(p.time)
+	m.changed = closed != m.closed
+	m.closed = closed
+}
+func (m *timeSwitch) breakpoint(after float64) float64 {
+	res := math.Inf(1)
+	for _, t := range []float64{m.closeTime, m.openTime} {
+		if t > after && t < res {
+			res = t
+		}
+	}
+	return res
+}
+func (m *timeSwitch) switched() bool {
+	return m.changed
+}
+func (m *timeSwitch) Parameters() map[string]float64 {
+	return map[string]float64{
+		"On resistance":  m.onResistance,
+		"Off resistance": m.offResistance,
+		"Close time":     m.closeTime,
+		"Open time":      m.openTime,
+	}
+}
+func (m *timeSwitch) UpdateParameter(name string, value float64) {
+	switch name {
+	case "On resistance":
+		m.onResistance = value
+	case "Off resistance":
+		m.offResistance = value
+	case "Close time":
+		m.closeTime = value
+	case "Open time":
+		m.openTime = value
+	}
+}
+
+// voltageSwitch closes when its control voltage rises above
+// threshold+hysteresis/2 and opens when it falls below
+// threshold-hysteresis/2.  The state is changed only by accepted steps,
+// so Newton-Raphson iterations of a step cannot flip it back and forth,
+// and the step crossing the threshold is shortened until it ends
+// right at the crossing.  At the operating point there is no accepted
+// state yet, so the iterations choose it on their own,
+// and the small-signal model takes the state they have chosen.
+type voltageSwitch struct {
+	onResistance  float64
+	offResistance float64
+	threshold     float64
+	hysteresis    float64
+	closed        bool
+	changed       bool
+}
+
+func newVoltageSwitch() *voltageSwitch {
+	return &voltageSwitch{
+		onResistance:  0.001,
+		offResistance: 1000000000.0,
+		hysteresis:    0.1,
+	}
+}
+
+func (m *voltageSwitch) terminalNames() []string {
+	return []string{"n", "p", "cn", "cp"}
+}
+func (m *voltageSwitch) stamp(p *port) {
+	p.conductance(1, 0, m.conductance(p))
+}
+func (m *voltageSwitch) terminalCurrent(p *port) float64 {
+	return m.conductance(p) * p.voltage(1, 0)
+}
+
+func (m *voltageSwitch) conductance(p *port) float64 {
+	closed := m.closed
+	if p.dc || p.ac {
+		closed = m.closes(p)
+	}
+	if closed {
+		return 1 / m.onResistance
+	}
+	return 1 / m.offResistance
+}
+
+func (m *voltageSwitch) closes(p *port) bool {
+	control := p.voltage(controlPositive, controlNegative)
+	if m.closed {
+		return control > m.threshold-m.hysteresis/2
+	}
+	return control > m.threshold+m.hysteresis/2
+}
+
+func (m *voltageSwitch) reset() {
+	m.closed = false
+	m.changed = false
+}
+func (m *voltageSwitch) accept(p *port) {
+	closed := m.closes(p)
+	m.changed = closed != m.closed
+	m.closed = closed
+}
+func (m *voltageSwitch) truncation(p *port) float64 {
+	if m.closes(p) != m.closed {
+		return math.Inf(1)
+	}
+	return 0
+}
+func (m *voltageSwitch) breakpoint(after float64) float64 {
+	return math.Inf(1)
+}
+func (m *voltageSwitch) switched() bool {
+	return m.changed
+}
+
+func (m *voltageSwitch) Parameters() map[string]float64 {
+	return map[string]float64{
+		"On resistance":  m.onResistance,
+		"Off resistance": m.offResistance,
+		"Threshold":      m.threshold,
+		"Hysteresis":     m.hysteresis,
+	}
+}
+func (m *voltageSwitch) UpdateParameter(name string, value float64) {
+	switch name {
+	case "On resistance":
+		m.onResistance = value
+	case "Off resistance":
+		m.offResistance = value
+	case "Threshold":
+		m.threshold = value
+	case "Hysteresis":
+		m.hysteresis = value
+	}
+}
@@ -0,0 +1,79 @@
+package cirsim
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTimeSwitchClosesAfterBreakpoint(t *testing.T) {
+	sim := newTestSimulation(3,
+		settings{"vdc", []int{0, 1}},
+		settings{"switch", []int{1, 2}},
+		settings{"resistor", []int{2, 0}})
+	sim.ModelerOfComponent(2).UpdateParameter("Resistance", 1000)
+	for m := BackwardEuler; m <= Gear; m++ {
+		sim.SetMethod(m)
+		sim.Simulate()
+		times := sim.Times()
+		for i, v := range sim.VoltagesOfNode(2) {
+			// the step ending at the breakpoint is still in the old state:
+			closed := 0.002 < times[i] && times[i] <= 0.006
+			if closed != (v > 0.5) {
+				t.Errorf("%v: %v at %vs", m, v, times[i])
+			}
+		}
+	}
+}
+
+func TestVoltageSwitchFollowsControl(t *testing.T) {
+	// the sine of 1kHz closes the switch above 0.55 and opens below 0.45:
+	sim := newTestSimulation(4,
+		settings{"voltage", []int{0, 1}},
+		settings{"vdc", []int{0, 2}},
+		settings{"vswitch", []int{3, 2, 0, 1}},
+		settings{"resistor", []int{3, 0}})
+	sim.ModelerOfComponent(2).UpdateParameter("Threshold", 0.5)
+	closing := math.Asin(0.55) / (2 * math.Pi * 1000)
+	opening := 0.0005 - math.Asin(0.45)/(2*math.Pi*1000)
+	for m := BackwardEuler; m <= Gear; m++ {
+		sim.SetMethod(m)
+		sim.Simulate()
+		if len(sim.Unconverged()) != 0 {
+			t.Fatalf("%v: unconverged at %v", m, sim.Unconverged())
+		}
+		times := sim.Times()
+		for i, v := range sim.VoltagesOfNode(3) {
+			cycle := math.Mod(times[i], 0.001)
+			if math.Abs(cycle-closing) < 1e-9 ||
+				math.Abs(cycle-opening) < 1e-9 {
+				continue
+			}
+			closed := closing < cycle && cycle < opening
+			if closed != (v > 0.5) {
+				t.Errorf("%v: %v at %vs", m, v, times[i])
+			}
+		}
+	}
+}
+
+func TestClosedVoltageSwitchInAC(t *testing.T) {
+	// the closed switch and the load of the same resistance divide
+	// the input in half at every frequency:
+	sim := newTestSimulation(4,
+		settings{"voltage", []int{0, 1}},
+		settings{"vswitch", []int{2, 1, 0, 3}},
+		settings{"resistor", []int{2, 0}},
+		settings{"vdc", []int{0, 3}})
+	sim.ModelerOfComponent(1).UpdateParameter("Threshold", 0.5)
+	sim.ModelerOfComponent(2).UpdateParameter("Resistance", 0.001)
+	sim.ModelerOfComponent(3).UpdateParameter("DC", 1)
+	_, magnitudes, _, converged := sim.AC(1, 1000000, 7, true)
+	if !converged {
+		t.Fatal("AC analysis did not converge")
+	}
+	for k, m := range magnitudes[2] {
+		if !near(m, 0.5, 1e-6) {
+			t.Errorf("point %d: magnitude %v, want 0.5", k, m)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package cirsim
+
+import (
+	"log"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// TransferFunction returns the small-signal gain from the source
+// to the node voltage at the operating point together with the input
+// resistance seen by the source and the output resistance at the node.
+// The gain is in V/V for voltage sources and in V/A for current ones.
+func (sim *simulation) TransferFunction(input, node int) (
+	gain, inputResistance, outputResistance float64, converged bool,
+) {
+	c := sim.components[input]
+	m, ok := c.Modeler.(exciter)
+	if !ok {
+		log.Fatal("transfer function input is not a source")
+	}
+	l, converged := sim.linearize()
+	rows, N := l.conductance.Dims()
+	s := newSystem(len(sim.nodeVoltages), sim.branches)
+	m.excite(sim.port(c, s, l.step, l.solution), 1)
+	x := mat.NewVecDense(N, nil)
+	if err := x.SolveVec(l.conductance, s.currents); err != nil {
+		converged = false
+	}
+	gain = x.AtVec(node)
+	// the input current is what the source itself adds
+	// to the current balance of its positive terminal:
+	own := newSystem(len(sim.nodeVoltages), sim.branches)
+	c.stamp(sim.port(c, own, l.step, l.solution))
+	flow := mat.NewVecDense(rows, nil)
+	flow.MulVec(own.matrix, x)
+	p := sim.port(c, s, l.step, x)
+	current := s.currents.AtVec(p.node(1)) - flow.AtVec(p.node(1))
+	inputResistance = p.voltage(1, 0) / current
+	// the output resistance is the voltage caused by the unit current
+	// injected into the node from the ground with all sources turned off:
+	unit := mat.NewVecDense(rows, nil)
+	unit.SetVec(node, 1)
+	unit.SetVec(0, -1)
+	if err := x.SolveVec(l.conductance, unit); err != nil {
+		converged = false
+	}
+	outputResistance = x.AtVec(node)
+	return gain, inputResistance, outputResistance, converged
+}
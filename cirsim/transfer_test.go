@@ -0,0 +1,25 @@
+package cirsim
+
+import "testing"
+
+func TestTransferFunctionOfDivider(t *testing.T) {
+	sim := newTestSimulation(3,
+		settings{"vdc", []int{0, 1}},
+		settings{"resistor", []int{1, 2}},
+		settings{"resistor", []int{2, 0}})
+	sim.ModelerOfComponent(1).UpdateParameter("Resistance", 1000)
+	sim.ModelerOfComponent(2).UpdateParameter("Resistance", 3000)
+	gain, in, out, converged := sim.TransferFunction(0, 2)
+	if !converged {
+		t.Fatal("transfer function did not converge")
+	}
+	if !near(gain, 0.75, 1e-6) {
+		t.Errorf("gain %v, want 0.75", gain)
+	}
+	if !near(in, 4000, 0.01) {
+		t.Errorf("input resistance %v, want 4000", in)
+	}
+	if !near(out, 750, 0.01) {
+		t.Errorf("output resistance %v, want 750", out)
+	}
+}
@@ -0,0 +1,434 @@
+package cirsim
+
+import (
+	"fmt"
+	"log"
+	"math"
+)
+
+// waveform is the value of a source over time.  Its breakpoints are
+// the corners, where the value changes its slope abruptly,
+// and its cycle is the period of repetition or zero, if it does not repeat.
+type waveform interface {
+	Modeler
+	value(time float64) float64
+	breakpoint(after float64) float64
+	cycle() float64
+}
+
+// WaveformNames returns names of waveforms, which sources can have.
+func WaveformNames() []string {
+	return []string{"dc", "sin", "pulse", "pwl", "exp", "sffm"}
+}
+
+func waveformName(w waveform) string {
+	switch w.(type) {
+	case *dc:
+		return "dc"
+	case *sine:
+		return "sin"
+	case *pulse:
+		return "pulse"
+	case *pwl:
+		return "pwl"
+	case *exp:
+		return "exp"
+	default:
+		return "sffm"
+	}
+}
+
+func newWaveform(name string) waveform {
+	switch name {
+	case "dc":
+		return &dc{1.0}
+	case "sin":
+		return newSine()
+	case "pulse":
+		return newPulse()
+	case "pwl":
+		return newPWL()
+	case "exp":
+		return newExp()
+	case "sffm":
+		return newSFFM()
+	default:
+		log.Fatal("wrong waveform name")
+		// compiler wants return here, but it will be never executed:
+		return nil
+	}
+}
+
+type dc struct {
+	level float64
+}
+
+func (w *dc) value(time float64) float64 {
+	return w.level
+}
+func (w *dc) breakpoint(after float64) float64 {
+	return math.Inf(1)
+}
+func (w *dc) cycle() float64 {
+	return 0
+}
+func (w *dc) Parameters() map[string]float64 {
+	return map[string]float64{"DC": w.level}
+}
+func (w *dc) UpdateParameter(name string, value float64) {
+	if name == "DC" {
+		w.level = value
+	}
+}
+
+type sine struct {
+	dc        float64
+	amplitude float64
+	frequency float64
+	phase     float64
+}
+
+func newSine() *sine {
+	return &sine{amplitude: 1.0, frequency: 1000.0}
+}
+
+func (w *sine) value(time float64) float64 {
+	return w.dc + w.amplitude*
+		math.Sin(time*w.frequency*2*math.Pi+w.phase*math.Pi/180)
+}
+func (w *sine) breakpoint(after float64) float64 {
+	return math.Inf(1)
+}
+func (w *sine) cycle() float64 {
+	if w.frequency == 0 {
+		return 0
+	}
+	return 1 / w.frequency
+}
+func (w *sine) Parameters() map[string]float64 {
+	return map[string]float64{
+		"DC":        w.dc,
+		"Amplitude": w.amplitude,
+		"Frequency": w.frequency,
+		"Phase":     w.phase,
+	}
+}
+func (w *sine) UpdateParameter(name string, value float64) {
+	switch name {
+	case "DC":
+		w.dc = value
+	case "Amplitude":
+		w.amplitude = value
+	case "Frequency":
+		w.frequency = value
+	case "Phase":
+		w.phase = value
+	}
+}
+
+// pulse is the SPICE PULSE: after the delay it rises from the initial
+// value to the pulsed one, stays there for the width and falls back,
+// repeating every period, if the period is positive.
+type pulse struct {
+	initial float64
+	pulsed  float64
+	delay   float64
+	rise    float64
+	fall    float64
+	width   float64
+	period  float64
+}
+
+func newPulse() *pulse {
+	return &pulse{
+		pulsed: 1.0,
+		delay:  0.001,
+		rise:   0.000001,
+		fall:   0.000001,
+		width:  0.002,
+		period: 0.005,
+	}
+}
+
+func (w *pulse) value(time float64) float64 {
+	if time < w.delay {
+		return w.initial
+	}
+	t := time - w.delay
+	if w.period > 0 {
+		t = math.Mod(t, w.period)
+	}
+	switch {
+	case t < w.rise:
+		return w.initial + (w.pulsed-w.initial)*t/w.rise
+	case t < w.rise+w.width:
+		return w.pulsed
+	case t < w.rise+w.width+w.fall:
+		return w.pulsed + (w.initial-w.pulsed)*(t-w.rise-w.width)/w.fall
+	}
+	return w.initial
+}
+
+func (w *pulse) breakpoint(after float64) float64 {
+	if after < w.delay {
+		return w.delay
+	}
+	cycle := 0.0
+	if w.period > 0 {
+		cycle = math.Floor((after - w.delay) / w.period)
+	}
+	corners := []float64{0, w.rise, w.rise + w.width, w.rise + w.width + w.fall}
+	for _, c := range corners {
+		if t := w.delay + cycle*w.period + c; t > after {
+			return t
+		}
+	}
+	if w.period > 0 {
+		return w.delay + (cycle+1)*w.period
+	}
+	return math.Inf(1)
+}
+
+// cycle of the pulse is its period only if there is no delay,
+// otherwise its value at zero time is never repeated.
+func (w *pulse) cycle() float64 {
+	if w.delay != 0 {
+		return 0
+	}
+	return math.Max(w.period, 0)
+}
+func (w *pulse) Parameters() map[string]float64 {
+	return map[string]float64{
+		"Initial value": w.initial,
+		"Pulsed value":  w.pulsed,
+		"Delay":         w.delay,
+		"Rise time":     w.rise,
+		"Fall time":     w.fall,
+		"Pulse width":   w.width,
+		"Period":        w.period,
+	}
+}
+func (w *pulse) UpdateParameter(name string, value float64) {
+	switch name {
+	case "Initial value":
+		w.initial = value
+	case "Pulsed value":
+		w.pulsed = value
+	case "Delay":
+		w.delay = value
+	case "Rise time":
+		w.rise = value
+	case "Fall time":
+		w.fall = value
+	case "Pulse width":
+		w.width = value
+	case "Period":
+		w.period = value
+	}
+}
+
+const pwlPoints = 8
+
+// pwl is the piecewise-linear waveform given by the table of points.
+// The table ends at the first point, which is not later than the previous
+// one, and the value is held constant outside of it.
+type pwl struct {
+	times  [pwlPoints]float64
+	values [pwlPoints]float64
+}
+
+func newPWL() *pwl {
+	return &pwl{
+		times:  [pwlPoints]float64{0, 0.001, 0.002, 0.004, 0.005},
+		values: [pwlPoints]float64{0, 0, 1, 1, 0},
+	}
+}
+
+// points returns the number of points in the table.
+func (w *pwl) points() int {
+	n := 1
+	for n != pwlPoints && w.times[n] > w.times[n-1] {
+		n++
+	}
+	return n
+}
+
+func (w *pwl) value(time float64) float64 {
+	n := w.points()
+	if time <= w.times[0] {
+		return w.values[0]
+	}
+	for i := 1; i != n; i++ {
+		if time < w.times[i] {
+			fraction := (time - w.times[i-1]) / (w.times[i] - w.times[i-1])
+			return w.values[i-1] + (w.values[i]-w.values[i-1])*fraction
+		}
+	}
+	return w.values[n-1]
+}
+
+func (w *pwl) breakpoint(after float64) float64 {
+	n := w.points()
+	for i := 0; i != n; i++ {
+		if w.times[i] > after {
+			return w.times[i]
+		}
+	}
+	return math.Inf(1)
+}
+
+func (w *pwl) cycle() float64 {
+	return 0
+}
+func (w *pwl) Parameters() map[string]float64 {
+	params := map[string]float64{}
+	for i := range w.times {
+		params[fmt.Sprintf("Time %d", i+1)] = w.times[i]
+		params[fmt.Sprintf("Value %d", i+1)] = w.values[i]
+	}
+	return params
+}
+func (w *pwl) fixed(parameter string) bool {
+	// times keep the order of points, which is the shape itself:
+	var i int
+	_, err := fmt.Sscanf(parameter, "Time %d", &i)
+	return err == nil
+}
+func (w *pwl) UpdateParameter(name string, value float64) {
+	var i int
+	if _, err := fmt.Sscanf(name, "Time %d", &i); err == nil {
+		if 1 <= i && i <= pwlPoints {
+			w.times[i-1] = value
+		}
+	} else if _, err := fmt.Sscanf(name, "Value %d", &i); err == nil {
+		if 1 <= i && i <= pwlPoints {
+			w.values[i-1] = value
+		}
+	}
+}
+
+// exp is the SPICE EXP: exponential approach to the pulsed value
+// after the rise delay and back to the initial one after the fall delay.
+type exp struct {
+	initial   float64
+	pulsed    float64
+	riseDelay float64
+	riseTau   float64
+	fallDelay float64
+	fallTau   float64
+}
+
+func newExp() *exp {
+	return &exp{
+		pulsed:    1.0,
+		riseDelay: 0.001,
+		riseTau:   0.0005,
+		fallDelay: 0.005,
+		fallTau:   0.0005,
+	}
+}
+
+func (w *exp) value(time float64) float64 {
+	v := w.initial
+	step := w.pulsed - w.initial
+	if time > w.riseDelay {
+		v += step * (1 - math.Exp((w.riseDelay-time)/w.riseTau))
+	}
+	if time > w.fallDelay {
+		v -= step * (1 - math.Exp((w.fallDelay-time)/w.fallTau))
+	}
+	return v
+}
+
+func (w *exp) breakpoint(after float64) float64 {
+	res := math.Inf(1)
+	for _, t := range []float64{w.riseDelay, w.fallDelay} {
+		if t > after && t < res {
+			res = t
+		}
+	}
+	return res
+}
+
+func (w *exp) cycle() float64 {
+	return 0
+}
+func (w *exp) Parameters() map[string]float64 {
+	return map[string]float64{
+		"Initial value":      w.initial,
+		"Pulsed value":       w.pulsed,
+		"Rise delay":         w.riseDelay,
+		"Rise time constant": w.riseTau,
+		"Fall delay":         w.fallDelay,
+		"Fall time constant": w.fallTau,
+	}
+}
+func (w *exp) UpdateParameter(name string, value float64) {
+	switch name {
+	case "Initial value":
+		w.initial = value
+	case "Pulsed value":
+		w.pulsed = value
+	case "Rise delay":
+		w.riseDelay = value
+	case "Rise time constant":
+		w.riseTau = value
+	case "Fall delay":
+		w.fallDelay = value
+	case "Fall time constant":
+		w.fallTau = value
+	}
+}
+
+// sffm is the single-frequency FM: the carrier sine,
+// which phase is modulated by the signal sine with the given index.
+type sffm struct {
+	dc        float64
+	amplitude float64
+	carrier   float64
+	index     float64
+	signal    float64
+}
+
+func newSFFM() *sffm {
+	return &sffm{
+		amplitude: 1.0,
+		carrier:   1000.0,
+		index:     5.0,
+		signal:    100.0,
+	}
+}
+
+func (w *sffm) value(time float64) float64 {
+	return w.dc + w.amplitude*math.Sin(2*math.Pi*w.carrier*time+
+		w.index*math.Sin(2*math.Pi*w.signal*time))
+}
+func (w *sffm) breakpoint(after float64) float64 {
+	return math.Inf(1)
+}
+func (w *sffm) cycle() float64 {
+	return 0
+}
+func (w *sffm) Parameters() map[string]float64 {
+	return map[string]float64{
+		"DC":                w.dc,
+		"Amplitude":         w.amplitude,
+		"Carrier frequency": w.carrier,
+		"Modulation index":  w.index,
+		"Signal frequency":  w.signal,
+	}
+}
+func (w *sffm) UpdateParameter(name string, value float64) {
+	switch name {
+	case "DC":
+		w.dc = value
+	case "Amplitude":
+		w.amplitude = value
+	case "Carrier frequency":
+		w.carrier = value
+	case "Modulation index":
+		w.index = value
+	case "Signal frequency":
+		w.signal = value
+	}
+}
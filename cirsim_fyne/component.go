@@ -2,8 +2,11 @@ package cirsim_fyne
 
 import (
 	"fmt"
-	"io"
 	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
@@ -18,58 +21,129 @@ type component struct {
 	modeler      cirsim.Modeler
 	pos          fyne.Position
 	modelName    string
-	nodes        [2]int
+	nodes        []int
 	currentRange chart.Range
 	chart        *canvas.Image
 	labels       []*widget.Label
 	entries      []*widget.Entry
+	// the choice of the waveform, if the component is a source:
+	waveformSelect *widget.Select
+	// shown runs and the view of the chart:
+	runs     []cirsim.Waveforms
+	index    int
+	spectrum bool
+	// distortion is shown over the spectrum, harmonics are in the table:
+	distortion *canvas.Text
+	harmonics  string
 }
 
 func (c *component) ModelName() string {
 	return c.modelName
 }
-func (c *component) Nodes() [2]int {
+func (c *component) Nodes() []int {
 	return c.nodes
 }
 
-func newComponent(settings io.Reader, r chart.Range) *component {
+func newComponent(settings string, r chart.Range) *component {
 	var c component
-	var a int
-	var b int
-	_, err := fmt.Fscanf(settings, "%f %f %s %d %d\n",
-		&c.pos.X, &c.pos.Y, &c.modelName, &a, &b,
+	fields := strings.Fields(settings)
+	if len(fields) < 3 {
+		return nil
+	}
+	_, err := fmt.Sscanf(strings.Join(fields[:3], " "), "%f %f %s",
+		&c.pos.X, &c.pos.Y, &c.modelName,
 	)
 	if err != nil {
 		return nil
 	}
-	if a <= 0 || b <= 0 {
-		log.Fatal("unconnected components in the circuit")
+	c.nodes = parseNodes(c.modelName, fields[3:])
+	for _, n := range c.nodes {
+		if n < 0 {
+			log.Fatal("unconnected components in the circuit")
+		}
 	}
-	c.nodes[0] = a - 1
-	c.nodes[1] = b - 1
 	c.currentRange = r
 	return &c
 }
 
-func (c *component) setupModeler(modeler cirsim.Modeler, update func()) {
+// parseNodes reads nodes of the component either in the order of
+// its terminals or as terminal=node pairs, like "c=3 b=2 e=1".
+// Terminals of a coupling are inductors numbered in the order
+// of components, like "l1=4 l2=7".
+func parseNodes(modelName string, fields []string) []int {
+	terminals := cirsim.Terminals(modelName)
+	if len(fields) != len(terminals) {
+		log.Fatal("wrong number of component terminals in the circuit")
+	}
+	nodes := make([]int, len(terminals))
+	for i := range nodes {
+		nodes[i] = -1
+	}
+	for i, f := range fields {
+		name, value, named := strings.Cut(f, "=")
+		if named {
+			i = indexOf(terminals, name)
+		} else {
+			value = name
+		}
+		n, err := strconv.Atoi(value)
+		if i < 0 || err != nil {
+			log.Fatal("wrong terminal in the circuit: ", f)
+		}
+		nodes[i] = n - 1
+	}
+	return nodes
+}
+
+func indexOf(names []string, name string) int {
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// setupModeler makes entries for parameters of the modeler.
+// The entry takes either a single value or several ones, listed
+// or given by ranges, which the update is expected to sweep over.
+// Sources also get the choice of their waveform, which is reshaped.
+func (c *component) setupModeler(
+	modeler cirsim.Modeler, waveform string,
+	update func(parameter string, values []float64),
+	reshape func(waveform string),
+) {
 	c.modeler = modeler
+	c.waveformSelect = nil
+	if waveform != "" {
+		c.waveformSelect = widget.NewSelect(cirsim.WaveformNames(), nil)
+		c.waveformSelect.Selected = waveform
+		c.waveformSelect.OnChanged = reshape
+	}
 	c.entries = make([]*widget.Entry, 0)
 	c.labels = make([]*widget.Label, 0)
 	params := c.modeler.Parameters()
-	for k, v := range params {
+	names := make([]string, 0, len(params))
+	for k := range params {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		v := params[k]
 		e := widget.NewEntry()
 		e.TextStyle.Monospace = true
 		e.SetPlaceHolder(k)
 		e.SetText(fmt.Sprintf("%f", v))
 		e.OnSubmitted = func(s string) {
-			var v float64
-			_, err := fmt.Sscanf(s+"\n", "%f\n", &v)
+			values, err := parseValues(s)
 			if err != nil {
-				e.SetText(fmt.Sprintf("%f", params[k]))
-			} else {
-				c.modeler.UpdateParameter(k, v)
-				update()
+				e.SetText(fmt.Sprintf("%f", c.modeler.Parameters()[k]))
+				return
+			}
+			if len(values) == 1 {
+				c.modeler.UpdateParameter(k, values[0])
 			}
+			update(k, values)
 		}
 		c.entries = append(c.entries, e)
 		l := widget.NewLabel(k)
@@ -78,7 +152,69 @@ func (c *component) setupModeler(modeler cirsim.Modeler, update func()) {
 	}
 }
 
-func (c *component) renderChart(currentOverTime []float64) {
+// parseValues reads values separated by spaces or commas,
+// where a value can also be the range start:stop:step,
+// which includes the stop, if the steps reach it.
+func parseValues(s string) ([]float64, error) {
+	fields := strings.Fields(strings.ReplaceAll(s, ",", " "))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no values")
+	}
+	var values []float64
+	for _, f := range fields {
+		bounds := strings.Split(f, ":")
+		if len(bounds) != 1 && len(bounds) != 3 {
+			return nil, fmt.Errorf("wrong range %s", f)
+		}
+		numbers := make([]float64, len(bounds))
+		for i, b := range bounds {
+			v, err := strconv.ParseFloat(b, 64)
+			if err != nil {
+				return nil, err
+			}
+			numbers[i] = v
+		}
+		if len(numbers) == 1 {
+			values = append(values, numbers[0])
+			continue
+		}
+		start, stop, step := numbers[0], numbers[1], numbers[2]
+		count := math.Floor((stop-start)/step*(1+rangePrecision)) + 1
+		if step == 0 || count < 1 || count > maxSweepValues ||
+			math.IsNaN(count) {
+			return nil, fmt.Errorf("wrong range %s", f)
+		}
+		for i := 0; i != int(count); i++ {
+			values = append(values, start+float64(i)*step)
+		}
+	}
+	if len(values) > maxSweepValues {
+		return nil, fmt.Errorf("too many values")
+	}
+	return values, nil
+}
+
+// renderChart draws currents over time of every run, overlaid,
+// or the spectrum of the first run.
+func (c *component) renderChart(runs []cirsim.Waveforms, i int) {
+	c.runs, c.index = runs, i
+	series := make([]chart.Series, len(runs))
+	for k, r := range runs {
+		series[k] = chart.ContinuousSeries{
+			XValues: r.Times,
+			YValues: r.Currents[i],
+		}
+	}
+	currentRange := c.currentRange
+	c.harmonics = ""
+	c.distortion = newDistortionText("")
+	if c.spectrum {
+		series = []chart.Series{spectrumSeries(runs[0], runs[0].Currents[i])}
+		currentRange = nil
+		var distortion string
+		c.harmonics, distortion = fourierTable(runs[0], runs[0].Currents[i])
+		c.distortion = newDistortionText(distortion)
+	}
 	graph := chart.Chart{
 		Width:        chartWidth,
 		Height:       chartHeight,
@@ -86,14 +222,9 @@ func (c *component) renderChart(currentOverTime []float64) {
 		XAxis:        chart.HideXAxis(),
 		YAxis: chart.YAxis{
 			Style: chart.Hidden(),
-			Range: c.currentRange,
-		},
-		Series: []chart.Series{
-			chart.ContinuousSeries{
-				XValues: chart.LinearRange(0, float64(len(currentOverTime))-1),
-				YValues: currentOverTime,
-			},
+			Range: currentRange,
 		},
+		Series: series,
 	}
 	writer := &chart.ImageWriter{}
 	graph.Render(chart.PNG, writer)
@@ -101,6 +232,14 @@ func (c *component) renderChart(currentOverTime []float64) {
 	c.chart = canvas.NewImageFromImage(img)
 }
 
+// renderTransfer draws the current over the values of the DC sweep,
+// which has no spectrum.
+func (c *component) renderTransfer(sweep cirsim.Waveforms, i int) {
+	c.spectrum = false
+	c.renderChart([]cirsim.Waveforms{sweep}, i)
+	c.runs = nil
+}
+
 type componentColorPalette struct{}
 
 func (*componentColorPalette) BackgroundColor() drawing.Color {
@@ -127,7 +266,24 @@ func (*componentColorPalette) TextColor() drawing.Color {
 	return drawing.ColorTransparent
 }
 func (*componentColorPalette) GetSeriesColor(index int) drawing.Color {
-	return drawing.Color{R: currentR, G: currentG, B: currentB, A: currentA}
+	return shade(drawing.Color{
+		R: currentR, G: currentG, B: currentB, A: currentA,
+	}, index)
+}
+
+// Tapped switches the chart to the spectrum view and back.
+func (c *component) Tapped(*fyne.PointEvent) {
+	if c.runs == nil {
+		return
+	}
+	c.spectrum = !c.spectrum
+	c.renderChart(c.runs, c.index)
+	canvas.Refresh(c)
+}
+
+// TappedSecondary shows the table of harmonics of the spectrum.
+func (c *component) TappedSecondary(e *fyne.PointEvent) {
+	showHarmonics(c, c.harmonics, e)
 }
 
 func (c *component) CreateRenderer() fyne.WidgetRenderer { return c }
@@ -135,6 +291,12 @@ func (c *component) Layout(s fyne.Size) {
 	const chartWidth = float32(chartWidth)
 	const chartHeight = float32(chartHeight)
 	pos := fyne.NewPos(0, 0)
+	if c.waveformSelect != nil {
+		height := c.waveformSelect.MinSize().Height
+		c.waveformSelect.Resize(fyne.NewSize(chartWidth, height))
+		c.waveformSelect.Move(pos)
+		pos.Y += height
+	}
 	for i, e := range c.entries {
 		c.labels[i].Resize(fyne.NewSize(chartWidth, c.labels[i].MinSize().Height))
 		c.labels[i].Move(pos)
@@ -145,11 +307,15 @@ func (c *component) Layout(s fyne.Size) {
 	}
 	c.chart.Resize(fyne.NewSize(chartWidth, chartHeight))
 	c.chart.Move(pos)
+	c.distortion.Move(pos)
 }
 func (c *component) MinSize() fyne.Size {
 	const chartWidth = float32(chartWidth)
 	const chartHeight = float32(chartHeight)
 	res := fyne.NewSize(chartWidth, chartHeight)
+	if c.waveformSelect != nil {
+		res.Height += c.waveformSelect.MinSize().Height
+	}
 	for _, e := range c.entries {
 		res.Height += e.MinSize().Height
 	}
@@ -169,10 +335,13 @@ func (c *component) Refresh() {
 func (c *component) Destroy() {}
 func (c *component) Objects() []fyne.CanvasObject {
 	res := []fyne.CanvasObject{}
+	if c.waveformSelect != nil {
+		res = append(res, c.waveformSelect)
+	}
 	for i, e := range c.entries {
 		res = append(res, c.labels[i])
 		res = append(res, e)
 	}
-	res = append(res, c.chart)
+	res = append(res, c.chart, c.distortion)
 	return res
 }
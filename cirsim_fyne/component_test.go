@@ -0,0 +1,35 @@
+package cirsim_fyne
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseValues(t *testing.T) {
+	for s, want := range map[string][]float64{
+		"1000":              {1000},
+		"1000, 10000":       {1000, 10000},
+		"1000:3000:1000":    {1000, 2000, 3000},
+		"0.1:0.3:0.1 1":     {0.1, 0.2, 0.3, 1},
+		"3:1:-1":            {3, 2, 1},
+		"0:1:0.4":           {0, 0.4, 0.8},
+		"1e3:1e3:1, 5:6:10": {1000, 5},
+	} {
+		values, err := parseValues(s)
+		if err != nil || len(values) != len(want) {
+			t.Errorf("%q: %v, %v, want %v", s, values, err, want)
+			continue
+		}
+		for i := range want {
+			if math.Abs(values[i]-want[i]) > 1e-9 {
+				t.Errorf("%q: %v, want %v", s, values, want)
+				break
+			}
+		}
+	}
+	for _, s := range []string{"", "a", "1:2", "1:2:0", "2:1:1", "0:1000:1"} {
+		if values, err := parseValues(s); err == nil {
+			t.Errorf("%q is parsed as %v", s, values)
+		}
+	}
+}
@@ -3,10 +3,12 @@ package cirsim_fyne
 import (
 	"fmt"
 	"io"
+	"math"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/widget"
+	"git.veresov.xyz/aversey/cirsim/cirsim"
 	"github.com/wcharczuk/go-chart/v2"
 	"github.com/wcharczuk/go-chart/v2/drawing"
 )
@@ -16,6 +18,13 @@ type node struct {
 	pos          fyne.Position
 	voltageRange chart.Range
 	chart        *canvas.Image
+	// shown runs, if the chart is over time, and its view:
+	runs     []cirsim.Waveforms
+	index    int
+	spectrum bool
+	// distortion is shown over the spectrum, harmonics are in the table:
+	distortion *canvas.Text
+	harmonics  string
 }
 
 func newNode(settings io.Reader, r chart.Range) *node {
@@ -28,7 +37,27 @@ func newNode(settings io.Reader, r chart.Range) *node {
 	return &n
 }
 
-func (n *node) renderChart(voltageOverTime []float64) {
+// renderChart draws voltages over time of every run, overlaid,
+// or the spectrum of the first run.
+func (n *node) renderChart(runs []cirsim.Waveforms, i int) {
+	n.runs, n.index = runs, i
+	series := make([]chart.Series, len(runs))
+	for k, r := range runs {
+		series[k] = chart.ContinuousSeries{
+			XValues: r.Times,
+			YValues: r.Voltages[i],
+		}
+	}
+	voltageRange := n.voltageRange
+	n.harmonics = ""
+	n.distortion = newDistortionText("")
+	if n.spectrum {
+		series = []chart.Series{spectrumSeries(runs[0], runs[0].Voltages[i])}
+		voltageRange = nil
+		var distortion string
+		n.harmonics, distortion = fourierTable(runs[0], runs[0].Voltages[i])
+		n.distortion = newDistortionText(distortion)
+	}
 	graph := chart.Chart{
 		Width:        chartWidth,
 		Height:       chartHeight,
@@ -36,12 +65,140 @@ func (n *node) renderChart(voltageOverTime []float64) {
 		XAxis:        chart.HideXAxis(),
 		YAxis: chart.YAxis{
 			Style: chart.Hidden(),
-			Range: n.voltageRange,
+			Range: voltageRange,
+		},
+		Series: series,
+	}
+	writer := &chart.ImageWriter{}
+	graph.Render(chart.PNG, writer)
+	img, _ := writer.Image()
+	n.chart = canvas.NewImageFromImage(img)
+}
+
+// renderTransfer draws the voltage over the values of the DC sweep,
+// which has no spectrum.
+func (n *node) renderTransfer(sweep cirsim.Waveforms, i int) {
+	n.spectrum = false
+	n.renderChart([]cirsim.Waveforms{sweep}, i)
+	n.runs = nil
+}
+
+// renderBode draws the gain in decibels and the phase
+// over the logarithmic frequency scale.
+func (n *node) renderBode(frequencies, magnitudes, phases []float64) {
+	n.runs = nil
+	n.distortion = newDistortionText("")
+	logFrequencies := make([]float64, len(frequencies))
+	gains := make([]float64, len(magnitudes))
+	gainRange := &chart.ContinuousRange{Min: math.Inf(1), Max: math.Inf(-1)}
+	for i := range frequencies {
+		logFrequencies[i] = math.Log10(frequencies[i])
+		gains[i] = math.Max(minGainInDB, 20*math.Log10(magnitudes[i]))
+		gainRange.Min = math.Min(gainRange.Min, gains[i])
+		gainRange.Max = math.Max(gainRange.Max, gains[i])
+	}
+	if gainRange.Max-gainRange.Min < 1 {
+		gainRange.Min -= 1
+		gainRange.Max += 1
+	}
+	graph := chart.Chart{
+		Width:        chartWidth,
+		Height:       chartHeight,
+		ColorPalette: &nodeColorPalette{},
+		XAxis:        chart.HideXAxis(),
+		YAxis: chart.YAxis{
+			Style: chart.Hidden(),
+			Range: gainRange,
+		},
+		YAxisSecondary: chart.YAxis{
+			Style: chart.Hidden(),
+			Range: &chart.ContinuousRange{Min: -180, Max: 180},
 		},
 		Series: []chart.Series{
 			chart.ContinuousSeries{
-				XValues: chart.LinearRange(0, float64(len(voltageOverTime))-1),
-				YValues: voltageOverTime,
+				XValues: logFrequencies,
+				YValues: gains,
+			},
+			chart.ContinuousSeries{
+				Style: chart.Style{StrokeColor: drawing.Color{
+					R: phaseR, G: phaseG, B: phaseB, A: phaseA,
+				}},
+				YAxis:   chart.YAxisSecondary,
+				XValues: logFrequencies,
+				YValues: phases,
+			},
+		},
+	}
+	writer := &chart.ImageWriter{}
+	graph.Render(chart.PNG, writer)
+	img, _ := writer.Image()
+	n.chart = canvas.NewImageFromImage(img)
+}
+
+// renderPoleZero draws poles and zeros on the s-plane
+// together with its imaginary axis, the border of stability.
+func (n *node) renderPoleZero(poles, zeros []complex128) {
+	n.runs = nil
+	n.distortion = newDistortionText("")
+	bound := 0.0
+	for _, r := range append(poles, zeros...) {
+		bound = math.Max(bound, math.Max(math.Abs(real(r)), math.Abs(imag(r))))
+	}
+	if bound == 0 {
+		bound = 1
+	}
+	bound *= poleZeroMargin
+	roots := func(rs []complex128) ([]float64, []float64) {
+		re := make([]float64, len(rs))
+		im := make([]float64, len(rs))
+		for i, r := range rs {
+			re[i], im[i] = real(r), imag(r)
+		}
+		return re, im
+	}
+	poleRe, poleIm := roots(poles)
+	zeroRe, zeroIm := roots(zeros)
+	graph := chart.Chart{
+		Width:        chartWidth,
+		Height:       chartHeight,
+		ColorPalette: &nodeColorPalette{},
+		XAxis: chart.XAxis{
+			Style: chart.Hidden(),
+			Range: &chart.ContinuousRange{Min: -bound, Max: bound},
+		},
+		YAxis: chart.YAxis{
+			Style: chart.Hidden(),
+			Range: &chart.ContinuousRange{Min: -bound, Max: bound},
+		},
+		Series: []chart.Series{
+			chart.ContinuousSeries{
+				Style: chart.Style{StrokeColor: drawing.Color{
+					R: voltageR, G: voltageG, B: voltageB, A: voltageA,
+				}},
+				XValues: []float64{0, 0},
+				YValues: []float64{-bound, bound},
+			},
+			chart.ContinuousSeries{
+				Style: chart.Style{
+					StrokeWidth: chart.Disabled,
+					DotWidth:    rootDotWidth,
+					DotColor: drawing.Color{
+						R: warningR, G: warningG, B: warningB, A: warningA,
+					},
+				},
+				XValues: poleRe,
+				YValues: poleIm,
+			},
+			chart.ContinuousSeries{
+				Style: chart.Style{
+					StrokeWidth: chart.Disabled,
+					DotWidth:    rootDotWidth,
+					DotColor: drawing.Color{
+						R: phaseR, G: phaseG, B: phaseB, A: phaseA,
+					},
+				},
+				XValues: zeroRe,
+				YValues: zeroIm,
 			},
 		},
 	}
@@ -77,17 +234,35 @@ func (*nodeColorPalette) TextColor() drawing.Color {
 	return drawing.ColorTransparent
 }
 func (*nodeColorPalette) GetSeriesColor(index int) drawing.Color {
-	return drawing.Color{R: voltageR, G: voltageG, B: voltageB, A: voltageA}
+	return shade(drawing.Color{
+		R: voltageR, G: voltageG, B: voltageB, A: voltageA,
+	}, index)
+}
+
+// Tapped switches the chart over time to the spectrum view and back.
+func (n *node) Tapped(*fyne.PointEvent) {
+	if n.runs == nil {
+		return
+	}
+	n.spectrum = !n.spectrum
+	n.renderChart(n.runs, n.index)
+	canvas.Refresh(n)
+}
+
+// TappedSecondary shows the table of harmonics of the spectrum.
+func (n *node) TappedSecondary(e *fyne.PointEvent) {
+	showHarmonics(n, n.harmonics, e)
 }
 
 func (n *node) CreateRenderer() fyne.WidgetRenderer { return n }
 func (n *node) Layout(s fyne.Size) {
 	n.chart.Resize(s)
 	n.chart.Move(fyne.NewPos(0, 0))
+	n.distortion.Move(fyne.NewPos(0, 0))
 }
 func (n *node) MinSize() fyne.Size { return n.chart.MinSize() }
 func (n *node) Refresh()           {}
 func (n *node) Destroy()           {}
 func (n *node) Objects() []fyne.CanvasObject {
-	return []fyne.CanvasObject{n.chart}
+	return []fyne.CanvasObject{n.chart, n.distortion}
 }
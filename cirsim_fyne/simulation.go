@@ -1,10 +1,12 @@
 package cirsim_fyne
 
 import (
+	"bufio"
 	"fmt"
 	"image/color"
 	"io"
 	"log"
+	"math"
 	"os"
 
 	"fyne.io/fyne/v2"
@@ -14,6 +16,7 @@ import (
 	"fyne.io/fyne/v2/widget"
 	"git.veresov.xyz/aversey/cirsim/cirsim"
 	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
 )
 
 const (
@@ -35,35 +38,81 @@ const (
 	voltageG             = 239
 	voltageB             = 3
 	voltageA             = 255
+	warningR             = 220
+	warningG             = 20
+	warningB             = 60
+	warningA             = 255
+	phaseR               = 240
+	phaseG               = 128
+	phaseB               = 0
+	phaseA               = 255
+)
+
+const (
+	transient   = "Transient"
+	ac          = "AC"
+	poleZero    = "Pole-zero"
+	dcSweep     = "DC sweep"
+	acStart     = 1.0
+	acStop      = 1000000.0
+	acPoints    = 200
+	minGainInDB = -240.0
+	shadeFactor = 0.75
+	// spectra are taken over the last cycles of sources, if there are
+	// such cycles, or over the last part of the period otherwise,
+	// and show the first bins only together with harmonics:
+	spectrumPeriods  = 5
+	spectrumPart     = 0.5
+	spectrumBins     = 100
+	fourierHarmonics = 9
+	cyclePrecision   = 0.000001
+	// parameters are swept over a limited number of values,
+	// and ranges reach their stop despite rounding of steps:
+	maxSweepValues = 100
+	rangePrecision = 0.000001
+	// the s-plane shows roots with the margin and marks them by dots:
+	poleZeroMargin = 1.2
+	rootDotWidth   = 3.0
 )
 
 type simulation struct {
 	sim          cirsim.Simulator
+	container    *fyne.Container
 	size         fyne.Size
 	nodes        []*node
 	components   []*component
 	voltageRange chart.ContinuousRange
 	currentRange chart.ContinuousRange
 	periodEntry  *widget.Entry
+	methodSelect *widget.Select
+	analysis     string
 	voltageLabel *canvas.Text
 	currentLabel *canvas.Text
+	warningLabel *canvas.Text
+
+	// parametric sweep shown, if there are sweep values:
+	sweepComponent int
+	sweepParameter string
+	sweepValues    []float64
 }
 
 func New() fyne.CanvasObject {
 	background := canvas.NewRectangle(color.White)
 	circuit := canvas.NewImageFromFile("circuit.svg")
-	settings, err := os.Open("circuit")
+	file, err := os.Open("circuit")
 	if err != nil {
 		log.Fatal(err)
 	}
+	settings := bufio.NewReader(file)
 	var sim simulation
 	sim.voltageRange = chart.ContinuousRange{Min: 0, Max: 0}
 	sim.currentRange = chart.ContinuousRange{Min: 0, Max: 0}
 	fmt.Fscanf(settings, "%f %f\n\n", &sim.size.Width, &sim.size.Height)
 	cont := container.New(&sim, sim.newPanel(settings), background, circuit)
+	sim.container = cont
 	sim.addNodes(cont, settings)
 	sim.addComponents(cont, settings)
-	settings.Close()
+	file.Close()
 	components := make([]cirsim.ComponentSettings, len(sim.components))
 	for i := range components {
 		components[i] = sim.components[i]
@@ -71,6 +120,7 @@ func New() fyne.CanvasObject {
 	sim.sim = cirsim.New(len(sim.nodes), components)
 	sim.periodEntry.SetPlaceHolder(
 		fmt.Sprintf("default: %fs", sim.sim.Period()))
+	sim.methodSelect.SetSelected(sim.sim.Method().String())
 	sim.setupComponentModelers()
 	sim.update()
 	return cont
@@ -89,17 +139,39 @@ func (sim *simulation) newPanel(settings io.Reader) *fyne.Container {
 		color.RGBA{R: currentR, G: currentG, B: currentB, A: currentA},
 	)
 	sim.currentLabel.TextStyle.Monospace = true
+	sim.warningLabel = canvas.NewText("",
+		color.RGBA{R: warningR, G: warningG, B: warningB, A: warningA})
+	sim.warningLabel.TextStyle.Monospace = true
 	periodLabel := widget.NewLabel("Period")
 	periodLabel.TextStyle.Monospace = true
 	sim.periodEntry = widget.NewEntry()
 	sim.periodEntry.TextStyle.Monospace = true
 	sim.periodEntry.OnSubmitted = sim.updatePeriod
+	methodLabel := widget.NewLabel("Method")
+	methodLabel.TextStyle.Monospace = true
+	methods := []string{}
+	for m := cirsim.BackwardEuler; m <= cirsim.Gear; m++ {
+		methods = append(methods, m.String())
+	}
+	sim.methodSelect = widget.NewSelect(methods, sim.updateMethod)
+	biasCheck := widget.NewCheck("Start at operating point", sim.updateBias)
+	steadyCheck := widget.NewCheck("Steady state", sim.updateSteady)
+	sim.analysis = transient
+	analysisSelect := widget.NewSelect(
+		[]string{transient, ac, poleZero, dcSweep}, sim.updateAnalysis)
+	analysisSelect.SetSelected(sim.analysis)
 	return container.NewHBox(
 		sim.voltageLabel,
 		sim.currentLabel,
+		sim.warningLabel,
 		layout.NewSpacer(),
 		periodLabel,
 		container.New(&entryLayout{}, sim.periodEntry),
+		methodLabel,
+		sim.methodSelect,
+		biasCheck,
+		steadyCheck,
+		analysisSelect,
 	)
 }
 
@@ -112,42 +184,319 @@ func (sim *simulation) addNodes(cont *fyne.Container, settings io.Reader) {
 	}
 }
 
-func (sim *simulation) addComponents(cont *fyne.Container, settings io.Reader) {
-	c := newComponent(settings, &sim.currentRange)
-	for c != nil {
-		sim.components = append(sim.components, c)
-		cont.Add(c)
-		c = newComponent(settings, &sim.currentRange)
+func (sim *simulation) addComponents(
+	cont *fyne.Container, settings *bufio.Reader,
+) {
+	for {
+		line, err := settings.ReadString('\n')
+		c := newComponent(line, &sim.currentRange)
+		if c != nil {
+			sim.components = append(sim.components, c)
+			cont.Add(c)
+		}
+		if err != nil {
+			break
+		}
 	}
 }
 
 func (sim *simulation) setupComponentModelers() {
 	for i := range sim.components {
-		sim.components[i].setupModeler(
-			sim.sim.ModelerOfComponent(i), sim.update)
+		sim.setupComponentModeler(i)
 	}
 }
 
-func (sim *simulation) update() {
+func (sim *simulation) setupComponentModeler(i int) {
+	c := sim.components[i]
+	c.setupModeler(sim.sim.ModelerOfComponent(i),
+		sim.sim.WaveformOfComponent(i),
+		func(parameter string, values []float64) {
+			sim.updateSweep(i, parameter, values)
+		},
+		func(waveform string) {
+			sim.updateWaveform(i, waveform)
+		})
+}
+
+// updateWaveform gives the source the new waveform, so its parameters
+// are new too, and the sweep over the old ones is stopped.
+func (sim *simulation) updateWaveform(component int, waveform string) {
+	sim.sim.SetWaveformOfComponent(component, waveform)
+	if component == sim.sweepComponent {
+		sim.sweepValues = nil
+	}
+	sim.setupComponentModeler(component)
+	// the component is placed by its new size:
+	sim.container.Refresh()
+	sim.components[component].Layout(sim.components[component].Size())
+	sim.update()
+}
+
+// updateSweep starts the parametric sweep, if there are several values,
+// or stops it, if the swept parameter gets the single value.
+func (sim *simulation) updateSweep(
+	component int, parameter string, values []float64,
+) {
+	if len(values) > 1 {
+		sim.sweepComponent = component
+		sim.sweepParameter = parameter
+		sim.sweepValues = values
+	} else if component == sim.sweepComponent &&
+		parameter == sim.sweepParameter {
+		sim.sweepValues = nil
+	}
+	sim.update()
+}
+
+// run returns waveforms of the sweep or of the single simulation.
+func (sim *simulation) run() []cirsim.Waveforms {
+	if sim.sweepValues != nil {
+		return sim.sim.ParametricSweep(
+			sim.sweepComponent, sim.sweepParameter, sim.sweepValues)
+	}
 	sim.sim.Simulate()
+	w := cirsim.Waveforms{
+		Times:       sim.sim.Times(),
+		Voltages:    make([][]float64, len(sim.nodes)),
+		Currents:    make([][]float64, len(sim.components)),
+		Unconverged: sim.sim.Unconverged(),
+		Cycle:       sim.sim.Cycle(),
+	}
+	for i := range w.Voltages {
+		w.Voltages[i] = sim.sim.VoltagesOfNode(i)
+	}
+	for i := range w.Currents {
+		w.Currents[i] = sim.sim.CurrentsOfComponent(i)
+	}
+	return []cirsim.Waveforms{w}
+}
+
+func (sim *simulation) update() {
+	if sim.analysis == dcSweep {
+		sim.updateDCSweep()
+		return
+	}
+	runs := sim.run()
 	sim.voltageRange.Min, sim.voltageRange.Max = sim.sim.VoltageRange()
 	sim.currentRange.Min, sim.currentRange.Max = sim.sim.CurrentRange()
+	for _, r := range runs {
+		for _, v := range r.Voltages {
+			extendRange(&sim.voltageRange, v)
+		}
+		for _, c := range r.Currents {
+			extendRange(&sim.currentRange, c)
+		}
+	}
+	sim.updateRangeLabels()
+	sim.warningLabel.Text = ""
+	for _, r := range runs {
+		if len(r.Unconverged) != 0 {
+			sim.warningLabel.Text = fmt.Sprintf(
+				" %d steps did not converge, first at %es ",
+				len(r.Unconverged), r.Unconverged[0])
+			break
+		}
+	}
+	sim.warningLabel.Refresh()
+	switch sim.analysis {
+	case ac:
+		sim.updateBode()
+	case poleZero:
+		sim.updatePoleZero()
+	default:
+		for i, n := range sim.nodes {
+			n.renderChart(runs, i)
+			n.Refresh()
+		}
+	}
+	for i, c := range sim.components {
+		c.renderChart(runs, i)
+		c.Refresh()
+	}
+}
+
+func (sim *simulation) updateRangeLabels() {
 	sim.voltageLabel.Text = fmt.Sprintf(" %e < voltage < %e ",
 		sim.voltageRange.Min, sim.voltageRange.Max)
 	sim.currentLabel.Text = fmt.Sprintf(" %e < current < %e ",
 		sim.currentRange.Min, sim.currentRange.Max)
 	sim.voltageLabel.Refresh()
 	sim.currentLabel.Refresh()
+}
+
+func extendRange(r *chart.ContinuousRange, values []float64) {
+	for _, v := range values {
+		r.Min = math.Min(r.Min, v)
+		r.Max = math.Max(r.Max, v)
+	}
+}
+
+// spectrumPeriodsOf returns the number of the last whole cycles
+// of the run the spectrum is taken over, zero if there are none.
+func spectrumPeriodsOf(w cirsim.Waveforms) int {
+	if w.Cycle == 0 {
+		return 0
+	}
+	duration := w.Times[len(w.Times)-1] - w.Times[0]
+	periods := math.Floor(duration / w.Cycle * (1 + cyclePrecision))
+	return int(math.Min(spectrumPeriods, periods))
+}
+
+func spectrumSeries(
+	w cirsim.Waveforms, values []float64,
+) chart.ContinuousSeries {
+	window := spectrumPart * (w.Times[len(w.Times)-1] - w.Times[0])
+	if periods := spectrumPeriodsOf(w); periods > 0 {
+		window = float64(periods) * w.Cycle
+	}
+	frequencies, magnitudes, _ := cirsim.Spectrum(w.Times, values, window)
+	bins := spectrumBins
+	if bins > len(frequencies) {
+		bins = len(frequencies)
+	}
+	return chart.ContinuousSeries{
+		XValues: frequencies[:bins],
+		YValues: magnitudes[:bins],
+	}
+}
+
+// fourierTable returns harmonics of the fundamental of sources
+// in the series with the total harmonic distortion as the text,
+// which is empty, if the run has no whole cycles of sources.
+func fourierTable(w cirsim.Waveforms, values []float64) (
+	table, distortion string,
+) {
+	periods := spectrumPeriodsOf(w)
+	if periods == 0 {
+		return "", ""
+	}
+	magnitudes, phases, thd := cirsim.Fourier(
+		w.Times, values, 1/w.Cycle, periods, fourierHarmonics)
+	distortion = fmt.Sprintf("THD %.3f%%", 100*thd)
+	table = fmt.Sprintf("%-3s %-11s %-12s %s\n",
+		"n", "frequency", "magnitude", "phase")
+	for h := range magnitudes {
+		table += fmt.Sprintf("%-3d %-11.4g %-12.6g %.2f\n",
+			h, float64(h)/w.Cycle, magnitudes[h], phases[h])
+	}
+	return table + distortion, distortion
+}
+
+func newDistortionText(distortion string) *canvas.Text {
+	text := canvas.NewText(distortion,
+		color.RGBA{R: warningR, G: warningG, B: warningB, A: warningA})
+	text.TextStyle.Monospace = true
+	return text
+}
+
+// showHarmonics pops the table of harmonics up over the chart,
+// if there is the table.
+func showHarmonics(o fyne.CanvasObject, harmonics string, e *fyne.PointEvent) {
+	if harmonics == "" {
+		return
+	}
+	table := widget.NewLabel(harmonics)
+	table.TextStyle.Monospace = true
+	c := fyne.CurrentApp().Driver().CanvasForObject(o)
+	widget.ShowPopUpAtPosition(table, c, e.AbsolutePosition)
+}
+
+// shade darkens the color for every next overlaid trace.
+func shade(c drawing.Color, index int) drawing.Color {
+	f := math.Pow(shadeFactor, float64(index))
+	return drawing.Color{
+		R: uint8(float64(c.R) * f),
+		G: uint8(float64(c.G) * f),
+		B: uint8(float64(c.B) * f),
+		A: c.A,
+	}
+}
+
+// updateBode shows Bode plots of nodes in place of their voltages.
+func (sim *simulation) updateBode() {
+	frequencies, magnitudes, phases, converged := sim.sim.AC(
+		acStart, acStop, acPoints, true)
+	if !converged {
+		sim.warningLabel.Text += " operating point did not converge "
+		sim.warningLabel.Refresh()
+	}
+	for i, n := range sim.nodes {
+		n.renderBode(frequencies, magnitudes[i], phases[i])
+		n.Refresh()
+	}
+}
+
+// updatePoleZero shows poles and zeros of transfer functions
+// from the first AC source to nodes in place of their voltages.
+func (sim *simulation) updatePoleZero() {
+	input := -1
+	for i := range sim.components {
+		if sim.sim.ModelerOfComponent(i).Parameters()["AC magnitude"] != 0 {
+			input = i
+			break
+		}
+	}
+	if input < 0 {
+		sim.warningLabel.Text += " no AC source for pole-zero analysis "
+		sim.warningLabel.Refresh()
+		return
+	}
+	for i, n := range sim.nodes {
+		poles, zeros, converged := sim.sim.PoleZero(input, i)
+		if !converged && i == 0 {
+			sim.warningLabel.Text += " operating point did not converge "
+			sim.warningLabel.Refresh()
+		}
+		n.renderPoleZero(poles, zeros)
+		n.Refresh()
+	}
+}
+
+// updateDCSweep shows voltages of nodes and currents of components
+// over the values of the swept parameter in place of their waveforms.
+func (sim *simulation) updateDCSweep() {
+	sim.warningLabel.Text = ""
+	defer sim.warningLabel.Refresh()
+	if sim.sweepValues == nil {
+		sim.warningLabel.Text = " no swept parameter for DC sweep "
+		return
+	}
+	voltages, currents, converged := sim.sim.DCSweep(
+		sim.sweepComponent, sim.sweepParameter, sim.sweepValues)
+	if !converged {
+		sim.warningLabel.Text = " operating point did not converge "
+	}
+	sweep := cirsim.Waveforms{
+		Times:    sim.sweepValues,
+		Voltages: voltages,
+		Currents: currents,
+	}
+	sim.voltageRange.Min, sim.voltageRange.Max = 0, 0
+	sim.currentRange.Min, sim.currentRange.Max = 0, 0
+	for _, v := range voltages {
+		extendRange(&sim.voltageRange, v)
+	}
+	for _, c := range currents {
+		extendRange(&sim.currentRange, c)
+	}
+	sim.updateRangeLabels()
 	for i, n := range sim.nodes {
-		n.renderChart(sim.sim.VoltagesOfNode(i))
+		n.renderTransfer(sweep, i)
 		n.Refresh()
 	}
 	for i, c := range sim.components {
-		c.renderChart(sim.sim.CurrentsOfComponent(i))
+		c.renderTransfer(sweep, i)
 		c.Refresh()
 	}
 }
 
+func (sim *simulation) updateAnalysis(analysis string) {
+	if analysis != sim.analysis {
+		sim.analysis = analysis
+		sim.update()
+	}
+}
+
 func (sim *simulation) updatePeriod(period string) {
 	var periodVal float64
 	_, err := fmt.Sscanf(period+"\n", "%f\n", periodVal)
@@ -159,6 +508,25 @@ func (sim *simulation) updatePeriod(period string) {
 	}
 }
 
+func (sim *simulation) updateMethod(method string) {
+	for m := cirsim.BackwardEuler; m <= cirsim.Gear; m++ {
+		if m.String() == method && m != sim.sim.Method() {
+			sim.sim.SetMethod(m)
+			sim.update()
+		}
+	}
+}
+
+func (sim *simulation) updateBias(initial bool) {
+	sim.sim.SetInitialOperatingPoint(initial)
+	sim.update()
+}
+
+func (sim *simulation) updateSteady(steady bool) {
+	sim.sim.SetSteadyState(steady)
+	sim.update()
+}
+
 func (l *simulation) MinSize(objects []fyne.CanvasObject) fyne.Size {
 	return objects[2].MinSize()
 }
@@ -0,0 +1,44 @@
+package cirsim_fyne
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"git.veresov.xyz/aversey/cirsim/cirsim"
+)
+
+func TestSpectrumPeriods(t *testing.T) {
+	times := []float64{0, 0.005, 0.01}
+	for cycle, want := range map[float64]int{
+		0: 0, 0.001: spectrumPeriods, 0.004: 2, 0.01 / 3: 3, 0.02: 0,
+	} {
+		w := cirsim.Waveforms{Times: times, Cycle: cycle}
+		if periods := spectrumPeriodsOf(w); periods != want {
+			t.Errorf("cycle %v: %d periods, want %d", cycle, periods, want)
+		}
+	}
+}
+
+func TestFourierTable(t *testing.T) {
+	// the square-like wave of odd harmonics has the known distortion:
+	w := cirsim.Waveforms{Cycle: 0.001}
+	var values []float64
+	for k := 0; k <= 10000; k++ {
+		time := 0.01 * float64(k) / 10000
+		w.Times = append(w.Times, time)
+		phase := 2 * math.Pi * 1000 * time
+		values = append(values, math.Sin(phase)+math.Sin(3*phase)/3)
+	}
+	table, distortion := fourierTable(w, values)
+	if !strings.HasPrefix(distortion, "THD 33.3") {
+		t.Errorf("distortion %q, want THD 33.3%%", distortion)
+	}
+	if lines := strings.Split(table, "\n"); len(lines) != fourierHarmonics+3 {
+		t.Errorf("table of %d lines:\n%s", len(lines), table)
+	}
+	w.Cycle = 0
+	if table, _ := fourierTable(w, values); table != "" {
+		t.Errorf("table without cycles:\n%s", table)
+	}
+}